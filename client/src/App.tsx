@@ -9,6 +9,7 @@ import Article from "./pages/Article";
 import Admin from "./pages/Admin";
 import Lab from "./pages/Lab";
 import Publish from "./pages/Publish";
+import OAuthCallback from "./pages/OAuthCallback";
 
 function Router() {
   return (
@@ -18,6 +19,7 @@ function Router() {
       <Route path={"/articles/:id"} component={Article} />
       <Route path={"/labs/:slug"} component={Lab} />
       <Route path={"/publish"} component={Publish} />
+      <Route path={"/oauth/callback"} component={OAuthCallback} />
       <Route path={"/404"} component={NotFound} />
       <Route component={NotFound} />
     </Switch>
@@ -2,14 +2,20 @@ import axios, { AxiosRequestConfig } from "axios";
 import { API_BASE_URL } from "@/const";
 
 const AUTH_KEY = "auth_token";
+const REFRESH_KEY = "refresh_token";
 
 export const getAuthToken = () => localStorage.getItem(AUTH_KEY) || "";
-export const setAuthToken = (token?: string) => {
+export const getRefreshToken = () => localStorage.getItem(REFRESH_KEY) || "";
+export const setAuthToken = (token?: string, refreshToken?: string) => {
   if (!token) {
     localStorage.removeItem(AUTH_KEY);
+    localStorage.removeItem(REFRESH_KEY);
     return;
   }
   localStorage.setItem(AUTH_KEY, token);
+  if (refreshToken) {
+    localStorage.setItem(REFRESH_KEY, refreshToken);
+  }
 };
 
 export const apiClient = axios.create({
@@ -29,6 +35,87 @@ apiClient.interceptors.request.use((config) => {
   return config;
 });
 
+type CaptchaChallenge = {
+  challenge: string;
+  difficulty: number;
+};
+
+const leadingZeroBits = (digest: Uint8Array) => {
+  let bits = 0;
+  for (const byte of digest) {
+    if (byte === 0) {
+      bits += 8;
+      continue;
+    }
+    return bits + Math.clz32(byte) - 24;
+  }
+  return bits;
+};
+
+// 求解工作量证明：找到 nonce 使 SHA-256(challenge + nonce) 的前 difficulty 位为 0
+export const solveCaptcha = async ({ challenge, difficulty }: CaptchaChallenge) => {
+  const encoder = new TextEncoder();
+  for (let nonce = 0; ; nonce++) {
+    const digest = await crypto.subtle.digest("SHA-256", encoder.encode(challenge + nonce));
+    if (leadingZeroBits(new Uint8Array(digest)) >= difficulty) {
+      return String(nonce);
+    }
+  }
+};
+
+// 受保护的接口返回 403 并附带题目时，自动求解后重试一次
+apiClient.interceptors.response.use(undefined, async (error) => {
+  const config = error.config as (AxiosRequestConfig & { _captchaRetried?: boolean }) | undefined;
+  const captcha = error.response?.status === 403 ? (error.response.data?.captcha as CaptchaChallenge | undefined) : undefined;
+  if (!config || !captcha || config._captchaRetried) {
+    return Promise.reject(error);
+  }
+  config._captchaRetried = true;
+  config.headers = {
+    ...config.headers,
+    "X-Captcha-Challenge": captcha.challenge,
+    "X-Captcha-Nonce": await solveCaptcha(captcha),
+  };
+  return apiClient.request(config);
+});
+
+// 同一时间只发起一次刷新：刷新令牌是一次性的，并发刷新会被服务端当作重放而吊销整个会话
+let refreshing: Promise<string> | null = null;
+
+const refreshAuthToken = () => {
+  if (!refreshing) {
+    refreshing = axios
+      .post<{ token: string; refresh_token: string }>(`${API_BASE_URL}/auth/refresh`, {
+        refresh_token: getRefreshToken(),
+      })
+      .then((res) => {
+        setAuthToken(res.data.token, res.data.refresh_token);
+        return res.data.token;
+      })
+      .catch((err) => {
+        setAuthToken();
+        throw err;
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+};
+
+// 访问令牌过期时用刷新令牌换取新令牌后重试一次
+apiClient.interceptors.response.use(undefined, async (error) => {
+  const config = error.config as (AxiosRequestConfig & { _authRetried?: boolean }) | undefined;
+  const isAuthRequest = config?.url?.startsWith("/auth/") && config.url !== "/auth/profile";
+  if (!config || error.response?.status !== 401 || config._authRetried || isAuthRequest || !getRefreshToken()) {
+    return Promise.reject(error);
+  }
+  config._authRetried = true;
+  const token = await refreshAuthToken();
+  config.headers = { ...config.headers, Authorization: `Bearer ${token}` };
+  return apiClient.request(config);
+});
+
 export const fetcher = async <T>(url: string, params?: Record<string, unknown>): Promise<T> => {
   const response = await apiClient.get<T>(url, { params });
   return response.data;
@@ -38,7 +38,7 @@ const defaultBodies = {
   category: JSON.stringify({ name: "New Category", slug: "new-category", description: "Desc" }, null, 2),
   tag: JSON.stringify({ name: "New Tag", slug: "new-tag" }, null, 2),
   comment: JSON.stringify(
-    { article_id: 1, content: "Nice post!", author: "Guest", email: "guest@example.com", website: "" },
+    { target_type: "article", target_id: 1, content: "Nice post!", author: "Guest", email: "guest@example.com", website: "" },
     null,
     2,
   ),
@@ -121,8 +121,8 @@ export default function Admin() {
   const [musicBody, setMusicBody] = useState(defaultBodies.music);
   const [playlistId, setPlaylistId] = useState("");
 
-  const setToken = useCallback((val: string) => {
-    setAuthToken(val);
+  const setToken = useCallback((val: string, refreshToken?: string) => {
+    setAuthToken(val, refreshToken);
     setTokenState(val);
   }, []);
 
@@ -253,7 +253,7 @@ export default function Admin() {
                   onClick={() =>
                     run("register", async () => {
                       const res = await apiPost<{ token?: string }>("/auth/register", auth);
-                      if ((res as any).token) setToken((res as any).token);
+                      if ((res as any).token) setToken((res as any).token, (res as any).refresh_token);
                       return res;
                     })
                   }
@@ -266,11 +266,20 @@ export default function Admin() {
                   disabled={busy === "login"}
                   onClick={() =>
                     run("login", async () => {
-                      const res = await apiPost<{ token?: string }>("/auth/login", {
+                      let res = await apiPost<{ token?: string }>("/auth/login", {
                         username: auth.username,
                         password: auth.password,
                       });
-                      if ((res as any).token) setToken((res as any).token);
+                      // 启用了两步验证时，用挑战令牌提交验证器中的验证码或恢复码
+                      if ((res as any).two_factor_required) {
+                        const code = window.prompt("Two-factor code or recovery code");
+                        if (!code) return res;
+                        res = await apiPost<{ token?: string }>("/auth/2fa/verify", {
+                          challenge_token: (res as any).challenge_token,
+                          code,
+                        });
+                      }
+                      if ((res as any).token) setToken((res as any).token, (res as any).refresh_token);
                       return res;
                     })
                   }
@@ -0,0 +1,67 @@
+import { Button } from "@/components/ui/button";
+import { Card, CardContent } from "@/components/ui/card";
+import { Input } from "@/components/ui/input";
+import { apiPost, setAuthToken } from "@/lib/api";
+import { useEffect, useState } from "react";
+import { useLocation } from "wouter";
+
+type Tokens = { token?: string; refresh_token?: string };
+
+// 第三方登录完成后后端跳转到这里，结果放在 URL 片段中
+export default function OAuthCallback() {
+  const [, setLocation] = useLocation();
+  const [params] = useState(() => new URLSearchParams(window.location.hash.slice(1)));
+  const [message, setMessage] = useState("");
+  const [challenge, setChallenge] = useState("");
+  const [code, setCode] = useState("");
+
+  useEffect(() => {
+    // 令牌不要留在地址栏和浏览历史中
+    window.history.replaceState(null, "", window.location.pathname);
+    if (params.get("error")) {
+      setMessage(`登录失败：${params.get("error")}`);
+    } else if (params.get("linked")) {
+      setMessage(`已关联 ${params.get("provider")} 账号`);
+    } else if (params.get("two_factor_required")) {
+      setChallenge(params.get("challenge_token") || "");
+      setMessage("请输入验证器中的验证码或恢复码");
+    } else if (params.get("token")) {
+      setAuthToken(params.get("token") || "", params.get("refresh_token") || "");
+      setMessage(params.get("created") ? "注册成功，已登录" : "登录成功");
+    } else {
+      setMessage("缺少登录结果");
+    }
+  }, [params]);
+
+  const verify = async () => {
+    try {
+      const res = await apiPost<Tokens>("/auth/2fa/verify", { challenge_token: challenge, code });
+      setAuthToken(res.token, res.refresh_token);
+      setChallenge("");
+      setMessage("登录成功");
+    } catch (err) {
+      setMessage("验证码错误或已过期");
+    }
+  };
+
+  return (
+    <div className="min-h-screen w-full flex items-center justify-center bg-background">
+      <Card className="w-full max-w-md mx-4">
+        <CardContent className="pt-8 pb-8 space-y-4 text-center">
+          <p className="text-sm">{message}</p>
+          {challenge && (
+            <div className="flex gap-2">
+              <Input value={code} onChange={(e) => setCode(e.target.value)} placeholder="123456" />
+              <Button onClick={verify} disabled={!code}>
+                验证
+              </Button>
+            </div>
+          )}
+          <Button variant="outline" onClick={() => setLocation("/")}>
+            返回首页
+          </Button>
+        </CardContent>
+      </Card>
+    </div>
+  );
+}
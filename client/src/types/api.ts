@@ -81,7 +81,8 @@ export interface Link {
 
 export interface Comment {
   id: number;
-  article_id: number;
+  target_type: "article" | "lab" | "music" | "playlist" | "guestbook";
+  target_id: number;
   content: string;
   author: string;
   email?: string;
@@ -0,0 +1,63 @@
+// importcomments 命令行导入 Disqus、Twikoo、Waline 的评论导出文件
+//
+// 用法（在 go_projects 目录下运行）：
+//
+//	go run ./cmd/importcomments -source disqus|twikoo|waline [-dry-run] [-json] <导出文件>
+package main
+
+import (
+	"blog-system/config"
+	"blog-system/database"
+	"blog-system/services"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	source := flag.String("source", "", "导出文件来源：disqus、twikoo 或 waline")
+	dryRun := flag.Bool("dry-run", false, "只匹配文章并输出报告，不写入数据库")
+	asJSON := flag.Bool("json", false, "以 JSON 格式输出报告")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: importcomments -source disqus|twikoo|waline [-dry-run] [-json] <导出文件>")
+		os.Exit(2)
+	}
+	filename := flag.Arg(0)
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Fatalf("打开导出文件失败: %v", err)
+	}
+	defer file.Close()
+
+	config.LoadConfig()
+	database.InitDB()
+
+	report, err := services.NewCommentImportService().Import(*source, filename, file, *dryRun)
+	if err != nil && report == nil {
+		log.Fatalf("导入评论失败: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		for _, thread := range report.Unmatched {
+			fmt.Printf("  - 未匹配 %s %s（%d 条评论）\n", thread.Thread, thread.Title, thread.Comments)
+		}
+		action := "导入"
+		if report.DryRun {
+			action = "可导入"
+		}
+		fmt.Printf("共 %d 条评论，%s %d 条，已存在 %d 条，忽略 %d 条，%d 个页面未匹配到文章\n",
+			report.Total, action, report.Imported, report.Skipped, report.Ignored, len(report.Unmatched))
+	}
+
+	if err != nil {
+		log.Fatalf("导入中断: %v", err)
+	}
+}
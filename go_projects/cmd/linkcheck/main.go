@@ -0,0 +1,82 @@
+// linkcheck 命令行检查文章与实验室内容中的失效链接
+//
+// 用法（在 go_projects 目录下运行）：
+//
+//	go run ./cmd/linkcheck [-external] [-article 12] [-concurrency 8] [-timeout 10] [-json]
+package main
+
+import (
+	"blog-system/config"
+	"blog-system/database"
+	"blog-system/services"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+	external := flag.Bool("external", false, "同时探测外部链接")
+	articleID := flag.String("article", "", "只检查指定 ID 的文章")
+	concurrency := flag.Int("concurrency", 0, "外部链接探测并发数（默认读取配置）")
+	timeout := flag.Int("timeout", 0, "外部链接请求超时秒数（默认读取配置）")
+	asJSON := flag.Bool("json", false, "以 JSON 格式输出报告")
+	flag.Parse()
+
+	config.LoadConfig()
+	database.InitDB()
+
+	opts := services.LinkCheckOptions{
+		CheckExternal: *external,
+		Concurrency:   *concurrency,
+		Timeout:       time.Duration(*timeout) * time.Second,
+	}
+	service := services.NewLinkCheckerService()
+
+	var reports []services.LinkReport
+	if *articleID != "" {
+		report, err := service.CheckArticle(*articleID, opts)
+		if err != nil {
+			log.Fatalf("检查文章 %s 失败: %v", *articleID, err)
+		}
+		reports = append(reports, *report)
+	} else {
+		all, err := service.CheckAll(opts)
+		if err != nil {
+			log.Fatalf("检查链接失败: %v", err)
+		}
+		reports = all
+	}
+
+	broken := 0
+	for _, report := range reports {
+		broken += len(report.Broken)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(reports)
+	} else {
+		for _, report := range reports {
+			if len(report.Broken) == 0 {
+				continue
+			}
+			fmt.Printf("[%s #%d] %s (%s)\n", report.SourceType, report.SourceID, report.Title, report.Slug)
+			for _, issue := range report.Broken {
+				if issue.StatusCode != 0 {
+					fmt.Printf("  - %-8s %s: %s (%d)\n", issue.Kind, issue.URL, issue.Reason, issue.StatusCode)
+				} else {
+					fmt.Printf("  - %-8s %s: %s\n", issue.Kind, issue.URL, issue.Reason)
+				}
+			}
+		}
+		fmt.Printf("共检查 %d 项内容，发现 %d 个失效链接\n", len(reports), broken)
+	}
+
+	if broken > 0 {
+		os.Exit(1)
+	}
+}
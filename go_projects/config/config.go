@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -23,7 +24,9 @@ type ServerConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string `yaml:"secret"`
+	Secret           string `yaml:"secret"`
+	AccessTTLMinutes int    `yaml:"access_ttl_minutes"`
+	RefreshTTLDays   int    `yaml:"refresh_ttl_days"`
 }
 
 type UploadConfig struct {
@@ -35,34 +38,213 @@ type MusicConfig struct {
 	Path string `yaml:"path"`
 }
 
+type LinkCheckConfig struct {
+	Concurrency int `yaml:"concurrency"`
+	Timeout     int `yaml:"timeout"`
+}
+
+type SlugConfig struct {
+	Strategy  string `yaml:"strategy"`
+	MaxLength int    `yaml:"max_length"`
+}
+
+type SiteConfig struct {
+	URL  string `yaml:"url"`
+	Name string `yaml:"name"`
+}
+
+type MailConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	Host        string   `yaml:"host"`
+	Port        int      `yaml:"port"`
+	Username    string   `yaml:"username"`
+	Password    string   `yaml:"password"`
+	From        string   `yaml:"from"`
+	FromName    string   `yaml:"from_name"`
+	Encryption  string   `yaml:"encryption"`
+	AdminEmails []string `yaml:"admin_emails"`
+	QueueSize   int      `yaml:"queue_size"`
+	MaxRetries  int      `yaml:"max_retries"`
+}
+
+type AvatarConfig struct {
+	Mirror  string `yaml:"mirror"`
+	Default string `yaml:"default"`
+	Size    int    `yaml:"size"`
+}
+
+type AkismetConfig struct {
+	Endpoint string `yaml:"endpoint"`
+	APIKey   string `yaml:"api_key"`
+	Blog     string `yaml:"blog"`
+}
+
+type SpamConfig struct {
+	Enabled          *bool         `yaml:"enabled"`
+	ApproveThreshold float64       `yaml:"approve_threshold"`
+	RejectThreshold  float64       `yaml:"reject_threshold"`
+	Blocklist        []string      `yaml:"blocklist"`
+	MaxLinks         int           `yaml:"max_links"`
+	MinSubmitSeconds int           `yaml:"min_submit_seconds"`
+	DuplicateHours   int           `yaml:"duplicate_hours"`
+	Akismet          AkismetConfig `yaml:"akismet"`
+}
+
+type CommentConfig struct {
+	AutoApproveTrusted *bool  `yaml:"auto_approve_trusted"`
+	AutoCloseDays      int    `yaml:"auto_close_days"`
+	MaxLength          int    `yaml:"max_length"`
+	MaxLinks           int    `yaml:"max_links"`
+	EditWindowMinutes  int    `yaml:"edit_window_minutes"`
+	GuestbookMode      string `yaml:"guestbook_mode"`
+}
+
+type AuthConfig struct {
+	PasswordResetTTLMinutes   int      `yaml:"password_reset_ttl_minutes"`
+	PasswordResetLimit        int      `yaml:"password_reset_limit"`
+	PasswordResetIPLimit      int      `yaml:"password_reset_ip_limit"`
+	RequireVerifiedEmail      []string `yaml:"require_verified_email"`
+	EmailVerificationTTLHours int      `yaml:"email_verification_ttl_hours"`
+	VerificationResendLimit   int      `yaml:"verification_resend_limit"`
+	VerificationResendIPLimit int      `yaml:"verification_resend_ip_limit"`
+	RequireAdmin2FA           bool     `yaml:"require_admin_2fa"`
+	TwoFactorChallengeMinutes int      `yaml:"two_factor_challenge_minutes"`
+}
+
+type CaptchaConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	Difficulty        int  `yaml:"difficulty"`
+	MaxDifficulty     int  `yaml:"max_difficulty"`
+	TTLSeconds        int  `yaml:"ttl_seconds"`
+	RateWindowSeconds int  `yaml:"rate_window_seconds"`
+	RateThreshold     int  `yaml:"rate_threshold"`
+}
+
+// OAuthProviderConfig 第三方登录提供方。type 为 oidc 时只需 issuer，端点通过 discovery 获取；
+// github 使用内置端点；oauth2 需要手动填写 auth_url、token_url 和 userinfo_url
+type OAuthProviderConfig struct {
+	Name         string   `yaml:"name"`
+	DisplayName  string   `yaml:"display_name"`
+	Type         string   `yaml:"type"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Issuer       string   `yaml:"issuer"`
+	AuthURL      string   `yaml:"auth_url"`
+	TokenURL     string   `yaml:"token_url"`
+	UserInfoURL  string   `yaml:"userinfo_url"`
+	Scopes       []string `yaml:"scopes"`
+	TrustEmail   bool     `yaml:"trust_email"`
+	AllowSignup  *bool    `yaml:"allow_signup"`
+}
+
+type OAuthConfig struct {
+	StateTTLMinutes int                   `yaml:"state_ttl_minutes"`
+	RedirectURL     string                `yaml:"redirect_url"`
+	Providers       []OAuthProviderConfig `yaml:"providers"`
+}
+
+type RBACConfig struct {
+	DefaultRole string              `yaml:"default_role"`
+	Roles       map[string][]string `yaml:"roles"`
+}
+
 type ConfigFile struct {
-	Database DatabaseConfig `yaml:"database"`
-	Server   ServerConfig   `yaml:"server"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	Upload   UploadConfig   `yaml:"upload"`
-	Music    MusicConfig    `yaml:"music"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Server    ServerConfig    `yaml:"server"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Upload    UploadConfig    `yaml:"upload"`
+	Music     MusicConfig     `yaml:"music"`
+	LinkCheck LinkCheckConfig `yaml:"link_check"`
+	Slug      SlugConfig      `yaml:"slug"`
+	Site      SiteConfig      `yaml:"site"`
+	Avatar    AvatarConfig    `yaml:"avatar"`
+	Spam      SpamConfig      `yaml:"spam"`
+	Mail      MailConfig      `yaml:"mail"`
+	Comment   CommentConfig   `yaml:"comment"`
+	Captcha   CaptchaConfig   `yaml:"captcha"`
+	Auth      AuthConfig      `yaml:"auth"`
+	OAuth     OAuthConfig     `yaml:"oauth"`
+	RBAC      RBACConfig      `yaml:"rbac"`
 }
 
 type Config struct {
-	DBType       string
-	DBHost       string
-	DBPort       string
-	DBUser       string
-	DBPassword   string
-	DBName       string
-	JWTSecret    string
-	ServerPort   string
-	ServerMode   string
-	UploadPath   string
-	MaxUploadSize int64
-	MusicPath    string
+	DBType                        string
+	DBHost                        string
+	DBPort                        string
+	DBUser                        string
+	DBPassword                    string
+	DBName                        string
+	JWTSecret                     string
+	JWTAccessTTLMinutes           int
+	JWTRefreshTTLDays             int
+	ServerPort                    string
+	ServerMode                    string
+	UploadPath                    string
+	MaxUploadSize                 int64
+	MusicPath                     string
+	LinkCheckConcurrency          int
+	LinkCheckTimeout              int
+	SlugStrategy                  string
+	SlugMaxLength                 int
+	SiteURL                       string
+	SiteName                      string
+	AvatarMirror                  string
+	AvatarDefault                 string
+	AvatarSize                    int
+	SpamEnabled                   bool
+	SpamApproveThreshold          float64
+	SpamRejectThreshold           float64
+	SpamBlocklist                 []string
+	SpamMaxLinks                  int
+	SpamMinSubmitSeconds          int
+	SpamDuplicateHours            int
+	AkismetEndpoint               string
+	AkismetAPIKey                 string
+	AkismetBlog                   string
+	MailEnabled                   bool
+	MailHost                      string
+	MailPort                      int
+	MailUsername                  string
+	MailPassword                  string
+	MailFrom                      string
+	MailFromName                  string
+	MailEncryption                string
+	MailAdminEmails               []string
+	MailQueueSize                 int
+	MailMaxRetries                int
+	CommentAutoApproveTrusted     bool
+	CommentAutoCloseDays          int
+	CommentMaxLength              int
+	CommentMaxLinks               int
+	CommentEditWindowMinutes      int
+	CommentGuestbookMode          string
+	CaptchaEnabled                bool
+	CaptchaDifficulty             int
+	CaptchaMaxDifficulty          int
+	CaptchaTTLSeconds             int
+	CaptchaRateWindowSeconds      int
+	CaptchaRateThreshold          int
+	AuthPasswordResetTTLMinutes   int
+	AuthPasswordResetLimit        int
+	AuthPasswordResetIPLimit      int
+	AuthRequireVerifiedEmail      []string
+	AuthEmailVerificationTTLHours int
+	AuthVerificationResendLimit   int
+	AuthVerificationResendIPLimit int
+	AuthRequireAdmin2FA           bool
+	AuthTwoFactorChallengeMinutes int
+	OAuthStateTTLMinutes          int
+	OAuthRedirectURL              string
+	OAuthProviders                []OAuthProviderConfig
+	RBACDefaultRole               string
+	RBACRoles                     map[string][]string
 }
 
 var AppConfig *Config
 
 func LoadConfig() {
 	configFile := "config/config.yaml"
-	
+
 	// 检查配置文件是否存在
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		log.Printf("配置文件 %s 不存在，使用默认配置", configFile)
@@ -88,18 +270,76 @@ func LoadConfig() {
 
 	// 转换为应用配置
 	AppConfig = &Config{
-		DBType:       getValueOrDefault(configFileData.Database.Type, "mysql"),
-		DBHost:       getValueOrDefault(configFileData.Database.Host, "localhost"),
-		DBPort:       getValueOrDefault(configFileData.Database.Port, "3306"),
-		DBUser:       getValueOrDefault(configFileData.Database.User, "root"),
-		DBPassword:   configFileData.Database.Password,
-		DBName:       getValueOrDefault(configFileData.Database.Name, "blog_system"),
-		JWTSecret:    getValueOrDefault(configFileData.JWT.Secret, "your-secret-key-change-this"),
-		ServerPort:   getValueOrDefault(configFileData.Server.Port, "8080"),
-		ServerMode:   getValueOrDefault(configFileData.Server.Mode, "debug"),
-		UploadPath:   getValueOrDefault(configFileData.Upload.Path, "./uploads"),
-		MaxUploadSize: configFileData.Upload.MaxSize,
-		MusicPath:    getValueOrDefault(configFileData.Music.Path, "./music"),
+		DBType:                        getValueOrDefault(configFileData.Database.Type, "mysql"),
+		DBHost:                        getValueOrDefault(configFileData.Database.Host, "localhost"),
+		DBPort:                        getValueOrDefault(configFileData.Database.Port, "3306"),
+		DBUser:                        getValueOrDefault(configFileData.Database.User, "root"),
+		DBPassword:                    configFileData.Database.Password,
+		DBName:                        getValueOrDefault(configFileData.Database.Name, "blog_system"),
+		JWTSecret:                     getValueOrDefault(configFileData.JWT.Secret, "your-secret-key-change-this"),
+		JWTAccessTTLMinutes:           getIntOrDefault(configFileData.JWT.AccessTTLMinutes, 15),
+		JWTRefreshTTLDays:             getIntOrDefault(configFileData.JWT.RefreshTTLDays, 30),
+		ServerPort:                    getValueOrDefault(configFileData.Server.Port, "8080"),
+		ServerMode:                    getValueOrDefault(configFileData.Server.Mode, "debug"),
+		UploadPath:                    getValueOrDefault(configFileData.Upload.Path, "./uploads"),
+		MaxUploadSize:                 configFileData.Upload.MaxSize,
+		MusicPath:                     getValueOrDefault(configFileData.Music.Path, "./music"),
+		LinkCheckConcurrency:          getIntOrDefault(configFileData.LinkCheck.Concurrency, 8),
+		LinkCheckTimeout:              getIntOrDefault(configFileData.LinkCheck.Timeout, 10),
+		SlugStrategy:                  getValueOrDefault(configFileData.Slug.Strategy, "pinyin"),
+		SlugMaxLength:                 getIntOrDefault(configFileData.Slug.MaxLength, 80),
+		SiteURL:                       strings.TrimRight(configFileData.Site.URL, "/"),
+		SiteName:                      getValueOrDefault(configFileData.Site.Name, "Blog"),
+		AvatarMirror:                  getValueOrDefault(configFileData.Avatar.Mirror, "https://www.gravatar.com/avatar/"),
+		AvatarDefault:                 getValueOrDefault(configFileData.Avatar.Default, "identicon"),
+		AvatarSize:                    getIntOrDefault(configFileData.Avatar.Size, 80),
+		SpamEnabled:                   configFileData.Spam.Enabled == nil || *configFileData.Spam.Enabled,
+		SpamApproveThreshold:          configFileData.Spam.ApproveThreshold,
+		SpamRejectThreshold:           getFloatOrDefault(configFileData.Spam.RejectThreshold, 10),
+		SpamBlocklist:                 configFileData.Spam.Blocklist,
+		SpamMaxLinks:                  getIntOrDefault(configFileData.Spam.MaxLinks, 2),
+		SpamMinSubmitSeconds:          getIntOrDefault(configFileData.Spam.MinSubmitSeconds, 3),
+		SpamDuplicateHours:            getIntOrDefault(configFileData.Spam.DuplicateHours, 24),
+		AkismetEndpoint:               getValueOrDefault(configFileData.Spam.Akismet.Endpoint, "https://rest.akismet.com"),
+		AkismetAPIKey:                 configFileData.Spam.Akismet.APIKey,
+		AkismetBlog:                   configFileData.Spam.Akismet.Blog,
+		MailEnabled:                   configFileData.Mail.Enabled,
+		MailHost:                      getValueOrDefault(configFileData.Mail.Host, "localhost"),
+		MailPort:                      getIntOrDefault(configFileData.Mail.Port, 25),
+		MailUsername:                  configFileData.Mail.Username,
+		MailPassword:                  configFileData.Mail.Password,
+		MailFrom:                      getValueOrDefault(configFileData.Mail.From, "noreply@localhost"),
+		MailFromName:                  configFileData.Mail.FromName,
+		MailEncryption:                getValueOrDefault(configFileData.Mail.Encryption, "none"),
+		MailAdminEmails:               configFileData.Mail.AdminEmails,
+		MailQueueSize:                 getIntOrDefault(configFileData.Mail.QueueSize, 100),
+		MailMaxRetries:                getIntOrDefault(configFileData.Mail.MaxRetries, 3),
+		CommentAutoApproveTrusted:     configFileData.Comment.AutoApproveTrusted == nil || *configFileData.Comment.AutoApproveTrusted,
+		CommentAutoCloseDays:          configFileData.Comment.AutoCloseDays,
+		CommentMaxLength:              getIntOrDefault(configFileData.Comment.MaxLength, 2000),
+		CommentMaxLinks:               getIntOrDefault(configFileData.Comment.MaxLinks, 5),
+		CommentEditWindowMinutes:      getIntOrDefault(configFileData.Comment.EditWindowMinutes, 15),
+		CommentGuestbookMode:          getValueOrDefault(configFileData.Comment.GuestbookMode, "open"),
+		CaptchaEnabled:                configFileData.Captcha.Enabled,
+		CaptchaDifficulty:             getIntOrDefault(configFileData.Captcha.Difficulty, 16),
+		CaptchaMaxDifficulty:          getIntOrDefault(configFileData.Captcha.MaxDifficulty, 24),
+		CaptchaTTLSeconds:             getIntOrDefault(configFileData.Captcha.TTLSeconds, 300),
+		CaptchaRateWindowSeconds:      getIntOrDefault(configFileData.Captcha.RateWindowSeconds, 600),
+		CaptchaRateThreshold:          getIntOrDefault(configFileData.Captcha.RateThreshold, 10),
+		AuthPasswordResetTTLMinutes:   getIntOrDefault(configFileData.Auth.PasswordResetTTLMinutes, 30),
+		AuthPasswordResetLimit:        getIntOrDefault(configFileData.Auth.PasswordResetLimit, 3),
+		AuthPasswordResetIPLimit:      getIntOrDefault(configFileData.Auth.PasswordResetIPLimit, 10),
+		AuthRequireVerifiedEmail:      configFileData.Auth.RequireVerifiedEmail,
+		AuthEmailVerificationTTLHours: getIntOrDefault(configFileData.Auth.EmailVerificationTTLHours, 48),
+		AuthVerificationResendLimit:   getIntOrDefault(configFileData.Auth.VerificationResendLimit, 3),
+		AuthVerificationResendIPLimit: getIntOrDefault(configFileData.Auth.VerificationResendIPLimit, 10),
+		AuthRequireAdmin2FA:           configFileData.Auth.RequireAdmin2FA,
+		AuthTwoFactorChallengeMinutes: getIntOrDefault(configFileData.Auth.TwoFactorChallengeMinutes, 5),
+		OAuthStateTTLMinutes:          getIntOrDefault(configFileData.OAuth.StateTTLMinutes, 10),
+		OAuthRedirectURL:              configFileData.OAuth.RedirectURL,
+		OAuthProviders:                configFileData.OAuth.Providers,
+		RBACDefaultRole:               getValueOrDefault(configFileData.RBAC.DefaultRole, "reader"),
+		RBACRoles:                     configFileData.RBAC.Roles,
 	}
 
 	// 如果 MaxUploadSize 为0，使用默认值
@@ -116,18 +356,59 @@ func LoadConfig() {
 
 func loadDefaultConfig() {
 	AppConfig = &Config{
-		DBType:       "mysql",
-		DBHost:       "localhost",
-		DBPort:       "3306",
-		DBUser:       "root",
-		DBPassword:   "",
-		DBName:       "blog_system",
-		JWTSecret:    "your-secret-key-change-this",
-		ServerPort:   "8080",
-		ServerMode:   "debug",
-		UploadPath:   "./uploads",
-		MaxUploadSize: 10485760,
-		MusicPath:    "./music",
+		DBType:                        "mysql",
+		DBHost:                        "localhost",
+		DBPort:                        "3306",
+		DBUser:                        "root",
+		DBPassword:                    "",
+		DBName:                        "blog_system",
+		JWTSecret:                     "your-secret-key-change-this",
+		JWTAccessTTLMinutes:           15,
+		JWTRefreshTTLDays:             30,
+		ServerPort:                    "8080",
+		ServerMode:                    "debug",
+		UploadPath:                    "./uploads",
+		MaxUploadSize:                 10485760,
+		MusicPath:                     "./music",
+		LinkCheckConcurrency:          8,
+		LinkCheckTimeout:              10,
+		SlugStrategy:                  "pinyin",
+		SlugMaxLength:                 80,
+		AvatarMirror:                  "https://www.gravatar.com/avatar/",
+		AvatarDefault:                 "identicon",
+		AvatarSize:                    80,
+		SpamEnabled:                   true,
+		SpamRejectThreshold:           10,
+		SpamMaxLinks:                  2,
+		SpamMinSubmitSeconds:          3,
+		SpamDuplicateHours:            24,
+		AkismetEndpoint:               "https://rest.akismet.com",
+		SiteName:                      "Blog",
+		MailHost:                      "localhost",
+		MailPort:                      25,
+		MailFrom:                      "noreply@localhost",
+		MailEncryption:                "none",
+		MailQueueSize:                 100,
+		MailMaxRetries:                3,
+		CommentAutoApproveTrusted:     true,
+		CommentMaxLength:              2000,
+		CommentMaxLinks:               5,
+		CommentEditWindowMinutes:      15,
+		CommentGuestbookMode:          "open",
+		CaptchaDifficulty:             16,
+		CaptchaMaxDifficulty:          24,
+		CaptchaTTLSeconds:             300,
+		CaptchaRateWindowSeconds:      600,
+		CaptchaRateThreshold:          10,
+		AuthPasswordResetTTLMinutes:   30,
+		AuthPasswordResetLimit:        3,
+		AuthPasswordResetIPLimit:      10,
+		AuthEmailVerificationTTLHours: 48,
+		AuthVerificationResendLimit:   3,
+		AuthVerificationResendIPLimit: 10,
+		AuthTwoFactorChallengeMinutes: 5,
+		OAuthStateTTLMinutes:          10,
+		RBACDefaultRole:               "reader",
 	}
 
 	// 创建必要的目录
@@ -142,10 +423,24 @@ func getValueOrDefault(value, defaultValue string) string {
 	return value
 }
 
+func getIntOrDefault(value, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func getFloatOrDefault(value, defaultValue float64) float64 {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
 // CreateDefaultConfig 创建默认配置文件
 func CreateDefaultConfig() error {
 	configFile := "config/config.yaml"
-	
+
 	// 检查文件是否已存在
 	if _, err := os.Stat(configFile); err == nil {
 		return fmt.Errorf("配置文件 %s 已存在", configFile)
@@ -166,7 +461,9 @@ func CreateDefaultConfig() error {
 			Mode: "debug",
 		},
 		JWT: JWTConfig{
-			Secret: "your-secret-key-change-this-in-production",
+			Secret:           "your-secret-key-change-this-in-production",
+			AccessTTLMinutes: 15,
+			RefreshTTLDays:   30,
 		},
 		Upload: UploadConfig{
 			Path:    "./uploads",
@@ -175,6 +472,76 @@ func CreateDefaultConfig() error {
 		Music: MusicConfig{
 			Path: "./music",
 		},
+		LinkCheck: LinkCheckConfig{
+			Concurrency: 8,
+			Timeout:     10,
+		},
+		Slug: SlugConfig{
+			Strategy:  "pinyin",
+			MaxLength: 80,
+		},
+		Site: SiteConfig{
+			URL:  "",
+			Name: "Blog",
+		},
+		Avatar: AvatarConfig{
+			Mirror:  "https://www.gravatar.com/avatar/",
+			Default: "identicon",
+			Size:    80,
+		},
+		Spam: SpamConfig{
+			RejectThreshold:  10,
+			MaxLinks:         2,
+			MinSubmitSeconds: 3,
+			DuplicateHours:   24,
+			Akismet: AkismetConfig{
+				Endpoint: "https://rest.akismet.com",
+			},
+		},
+		Mail: MailConfig{
+			Enabled:    false,
+			Host:       "localhost",
+			Port:       25,
+			From:       "noreply@localhost",
+			Encryption: "none",
+			QueueSize:  100,
+			MaxRetries: 3,
+		},
+		Comment: CommentConfig{
+			AutoCloseDays:     0,
+			MaxLength:         2000,
+			MaxLinks:          5,
+			EditWindowMinutes: 15,
+			GuestbookMode:     "open",
+		},
+		Captcha: CaptchaConfig{
+			Enabled:           false,
+			Difficulty:        16,
+			MaxDifficulty:     24,
+			TTLSeconds:        300,
+			RateWindowSeconds: 600,
+			RateThreshold:     10,
+		},
+		Auth: AuthConfig{
+			PasswordResetTTLMinutes:   30,
+			PasswordResetLimit:        3,
+			PasswordResetIPLimit:      10,
+			RequireVerifiedEmail:      []string{},
+			EmailVerificationTTLHours: 48,
+			VerificationResendLimit:   3,
+			VerificationResendIPLimit: 10,
+			RequireAdmin2FA:           false,
+			TwoFactorChallengeMinutes: 5,
+		},
+		OAuth: OAuthConfig{
+			StateTTLMinutes: 10,
+			RedirectURL:     "",
+			Providers:       []OAuthProviderConfig{},
+		},
+		RBAC: RBACConfig{
+			DefaultRole: "reader",
+			Roles:       map[string][]string{},
+		},
 	}
 
 	// 序列化为YAML
@@ -191,4 +558,3 @@ func CreateDefaultConfig() error {
 	log.Printf("默认配置文件已创建: %s", configFile)
 	return nil
 }
-
@@ -3,6 +3,7 @@ package controllers
 import (
 	"blog-system/models"
 	"blog-system/services"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -30,6 +31,9 @@ func (ac *ArticleController) GetArticles(c *gin.Context) {
 	}
 	if category := c.Query("category"); category != "" {
 		filters["category_id"] = category
+		if c.Query("include_children") == "true" {
+			filters["include_descendants"] = true
+		}
 	}
 	if tag := c.Query("tag"); tag != "" {
 		filters["tag_slug"] = tag
@@ -70,16 +74,19 @@ func (ac *ArticleController) GetArticle(c *gin.Context) {
 // CreateArticle 创建文章
 func (ac *ArticleController) CreateArticle(c *gin.Context) {
 	userID, _ := c.Get("user_id")
+	role := c.GetString("role")
 
 	var input struct {
-		Title      string   `json:"title" binding:"required"`
-		Content    string   `json:"content" binding:"required"`
-		Excerpt    string   `json:"excerpt"`
-		CoverImage string   `json:"cover_image"`
-		CategoryID uint     `json:"category_id"`
-		TagIDs     []uint   `json:"tag_ids"`
-		Status     string   `json:"status"`
-		IsTop      bool     `json:"is_top"`
+		Title      string     `json:"title" binding:"required"`
+		Slug       string     `json:"slug"`
+		Content    string     `json:"content" binding:"required"`
+		Excerpt    string     `json:"excerpt"`
+		CoverImage string     `json:"cover_image"`
+		CategoryID uint       `json:"category_id"`
+		TagIDs     []uint     `json:"tag_ids"`
+		Status     string     `json:"status"`
+		IsTop      bool       `json:"is_top"`
+		SEO        models.SEO `json:"seo"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -87,8 +94,14 @@ func (ac *ArticleController) CreateArticle(c *gin.Context) {
 		return
 	}
 
+	if input.Status == "published" && !services.HasPermission(role, services.PermArticlePublish) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied: publishing requires the article.publish permission"})
+		return
+	}
+
 	article := &models.Article{
 		Title:      input.Title,
+		Slug:       input.Slug,
 		Content:    input.Content,
 		Excerpt:    input.Excerpt,
 		CoverImage: input.CoverImage,
@@ -96,10 +109,14 @@ func (ac *ArticleController) CreateArticle(c *gin.Context) {
 		CategoryID: input.CategoryID,
 		Status:     input.Status,
 		IsTop:      input.IsTop,
+		SEO:        input.SEO,
 	}
 
 	createdArticle, err := ac.service.CreateArticle(article, input.TagIDs)
 	if err != nil {
+		if renderSlugError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create article"})
 		return
 	}
@@ -118,7 +135,7 @@ func (ac *ArticleController) CreateArticle(c *gin.Context) {
 func (ac *ArticleController) UpdateArticle(c *gin.Context) {
 	id := c.Param("id")
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
+	role := c.GetString("role")
 
 	// Check permission
 	article, err := ac.service.GetArticle(id)
@@ -127,20 +144,22 @@ func (ac *ArticleController) UpdateArticle(c *gin.Context) {
 		return
 	}
 
-	if role != "admin" && article.AuthorID != userID.(uint) {
+	if !services.HasPermission(role, services.PermArticleManage) && article.AuthorID != userID.(uint) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 		return
 	}
 
 	var input struct {
-		Title      string   `json:"title"`
-		Content    string   `json:"content"`
-		Excerpt    string   `json:"excerpt"`
-		CoverImage string   `json:"cover_image"`
-		CategoryID uint     `json:"category_id"`
-		TagIDs     []uint   `json:"tag_ids"`
-		Status     string   `json:"status"`
-		IsTop      bool     `json:"is_top"`
+		Title      string      `json:"title"`
+		Slug       string      `json:"slug"`
+		Content    string      `json:"content"`
+		Excerpt    string      `json:"excerpt"`
+		CoverImage string      `json:"cover_image"`
+		CategoryID uint        `json:"category_id"`
+		TagIDs     []uint      `json:"tag_ids"`
+		Status     string      `json:"status"`
+		IsTop      bool        `json:"is_top"`
+		SEO        *models.SEO `json:"seo"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -148,8 +167,14 @@ func (ac *ArticleController) UpdateArticle(c *gin.Context) {
 		return
 	}
 
+	if input.Status == "published" && article.Status != "published" && !services.HasPermission(role, services.PermArticlePublish) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied: publishing requires the article.publish permission"})
+		return
+	}
+
 	updateData := &models.Article{
 		Title:      input.Title,
+		Slug:       input.Slug,
 		Content:    input.Content,
 		Excerpt:    input.Excerpt,
 		CoverImage: input.CoverImage,
@@ -158,8 +183,11 @@ func (ac *ArticleController) UpdateArticle(c *gin.Context) {
 		IsTop:      input.IsTop,
 	}
 
-	updatedArticle, err := ac.service.UpdateArticle(id, updateData, input.TagIDs)
+	updatedArticle, err := ac.service.UpdateArticle(id, updateData, input.TagIDs, input.SEO)
 	if err != nil {
+		if renderSlugError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update article"})
 		return
 	}
@@ -170,11 +198,54 @@ func (ac *ArticleController) UpdateArticle(c *gin.Context) {
 	c.JSON(http.StatusOK, fullArticle)
 }
 
+// UpdateCommentSettings 修改文章评论设置
+func (ac *ArticleController) UpdateCommentSettings(c *gin.Context) {
+	id := c.Param("id")
+	userID, _ := c.Get("user_id")
+	role := c.GetString("role")
+
+	article, err := ac.service.GetArticle(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+
+	if !services.HasPermission(role, services.PermArticleManage) && article.AuthorID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	var input struct {
+		Mode          string `json:"mode" binding:"required"`
+		AutoCloseDays int    `json:"auto_close_days"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := ac.service.UpdateCommentSettings(id, input.Mode, input.AutoCloseDays)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCommentMode) || errors.Is(err, services.ErrInvalidAutoCloseDays) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comment_mode":            updated.CommentMode,
+		"comment_auto_close_days": updated.CommentAutoCloseDays,
+		"effective_mode":          services.EffectiveCommentMode(updated),
+	})
+}
+
 // DeleteArticle 删除文章
 func (ac *ArticleController) DeleteArticle(c *gin.Context) {
 	id := c.Param("id")
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
+	role := c.GetString("role")
 
 	article, err := ac.service.GetArticle(id)
 	if err != nil {
@@ -182,7 +253,7 @@ func (ac *ArticleController) DeleteArticle(c *gin.Context) {
 		return
 	}
 
-	if role != "admin" && article.AuthorID != userID.(uint) {
+	if !services.HasPermission(role, services.PermArticleManage) && article.AuthorID != userID.(uint) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 		return
 	}
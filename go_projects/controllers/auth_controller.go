@@ -3,6 +3,7 @@ package controllers
 import (
 	_ "blog-system/models"
 	"blog-system/services"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -29,26 +30,39 @@ func (ac *AuthController) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := ac.service.Register(input.Username, input.Email, input.Password)
+	user, err := ac.service.Register(input.Username, input.Email, input.Password, c.ClientIP())
 	if err != nil {
-		if err.Error() == "username already exists" {
+		switch {
+		case errors.Is(err, services.ErrUsernameTaken):
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-		} else {
+		case errors.Is(err, services.ErrEmailRegistered):
+			// 与需要验证邮箱时注册成功的响应相同，不透露邮箱是否已注册
+			respondRegistrationPending(c)
+		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		}
 		return
 	}
 
+	// 要求验证邮箱后才能登录时，先不签发令牌
+	if services.EmailVerificationRequired(services.VerifiedEmailForLogin) {
+		respondRegistrationPending(c)
+		return
+	}
+
 	// Generate token for immediate login
-	token, _, err := ac.service.Login(input.Username, input.Password)
+	tokens, _, err := ac.service.Login(input.Username, input.Password, sessionMeta(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "User created successfully",
-		"token":   token,
+		"message":            "User created successfully",
+		"token":              tokens.AccessToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
@@ -58,6 +72,14 @@ func (ac *AuthController) Register(c *gin.Context) {
 	})
 }
 
+// respondRegistrationPending 注册后需要查收邮件的统一响应，不包含账号信息
+func respondRegistrationPending(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"message":               "Registration received, please check your email to continue",
+		"verification_required": true,
+	})
+}
+
 // Login 用户登录
 func (ac *AuthController) Login(c *gin.Context) {
 	var input struct {
@@ -70,15 +92,36 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := ac.service.Login(input.Username, input.Password)
+	tokens, user, err := ac.service.Login(input.Username, input.Password, sessionMeta(c))
+	var challenge *services.TwoFactorChallenge
+	if errors.As(err, &challenge) {
+		// 密码正确但启用了两步验证，凭挑战令牌调用 /auth/2fa/verify 完成登录
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "Two-factor authentication required",
+			"two_factor_required": true,
+			"challenge_token":     challenge.ChallengeToken,
+			"expires_in":          challenge.ExpiresIn,
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		} else if errors.Is(err, services.ErrEmailNotVerified) || errors.Is(err, services.ErrAccountDisabled) ||
+			errors.Is(err, services.ErrPasswordResetRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
+		"message":            "Login successful",
+		"token":              tokens.AccessToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
@@ -89,6 +132,183 @@ func (ac *AuthController) Login(c *gin.Context) {
 	})
 }
 
+// Refresh 用刷新令牌换取新的令牌对，旧刷新令牌随即作废
+func (ac *AuthController) Refresh(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, _, err := ac.service.Refresh(input.RefreshToken, sessionMeta(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrAccountDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout 吊销当前会话的刷新令牌；all 为 true 时退出该用户的所有设备
+func (ac *AuthController) Logout(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+		All          bool   `json:"all"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ac.service.Logout(input.RefreshToken, input.All); err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// ChangePassword 修改密码，需要提供当前密码；成功后其他设备全部退出
+func (ac *AuthController) ChangePassword(c *gin.Context) {
+	var input struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required,min=6"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	tokens, err := ac.service.ChangePassword(userID.(uint), input.OldPassword, input.NewPassword, sessionMeta(c))
+	if err != nil {
+		if errors.Is(err, services.ErrWrongPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Password changed",
+		"token":              tokens.AccessToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	})
+}
+
+// ForgotPassword 发送找回密码邮件；无论邮箱是否注册都返回相同结果
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ac.service.RequestPasswordReset(input.Email, c.ClientIP()); err != nil {
+		switch {
+		case errors.Is(err, services.ErrTooManyRequests):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMailNotConfigured):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reset email"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword 使用邮件中的令牌设置新密码，所有已登录的会话随即失效
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ac.service.ResetPassword(input.Token, input.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in again"})
+}
+
+// VerifyEmail 验证邮件中的链接
+func (ac *AuthController) VerifyEmail(c *gin.Context) {
+	user, err := ac.service.VerifyEmail(c.Query("uid"), c.Query("exp"), c.Query("sig"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerifyLink) || errors.Is(err, services.ErrVerifyLinkExpired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Email verified",
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+}
+
+// ResendVerification 重新发送验证邮件；无论邮箱是否注册都返回相同结果
+func (ac *AuthController) ResendVerification(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ac.service.ResendVerification(input.Email, c.ClientIP()); err != nil {
+		switch {
+		case errors.Is(err, services.ErrTooManyRequests):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMailNotConfigured):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered and not yet verified, a verification link has been sent"})
+}
+
 // GetProfile 获取当前用户信息
 func (ac *AuthController) GetProfile(c *gin.Context) {
 	userID, _ := c.Get("user_id")
@@ -100,11 +320,18 @@ func (ac *AuthController) GetProfile(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
-		"role":     user.Role,
-		"avatar":   user.Avatar,
-		"bio":      user.Bio,
+		"id":                 user.ID,
+		"username":           user.Username,
+		"email":              user.Email,
+		"email_verified_at":  user.EmailVerifiedAt,
+		"two_factor_enabled": user.TOTPEnabledAt != nil,
+		"role":               user.Role,
+		"permissions":        services.RolePermissions(user.Role),
+		"avatar":             user.Avatar,
+		"bio":                user.Bio,
 	})
 }
+
+func sessionMeta(c *gin.Context) services.SessionMeta {
+	return services.SessionMeta{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"blog-system/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CaptchaController struct {
+	service services.CaptchaService
+}
+
+func NewCaptchaController(service services.CaptchaService) *CaptchaController {
+	return &CaptchaController{service: service}
+}
+
+// GetChallenge 签发工作量证明题目，?scope= 为 comment、register 或 like；未启用时返回 enabled=false
+func (cc *CaptchaController) GetChallenge(c *gin.Context) {
+	if !cc.service.Enabled() {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	challenge, err := cc.service.Issue(c.DefaultQuery("scope", services.CaptchaScopeComment), c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCaptchaScope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue captcha"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "captcha": challenge})
+}
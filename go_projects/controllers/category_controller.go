@@ -3,10 +3,14 @@ package controllers
 import (
 	"blog-system/models"
 	"blog-system/services"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
 )
 
 type CategoryController struct {
@@ -18,6 +22,25 @@ func NewCategoryController(service services.CategoryService) *CategoryController
 }
 
 func (cc *CategoryController) GetCategories(c *gin.Context) {
+	if c.Query("with_stats") == "true" {
+		categories, err := cc.service.GetCategoriesWithStats()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+			return
+		}
+		if c.Query("hide_empty") == "true" {
+			filtered := make([]models.CategoryWithStats, 0, len(categories))
+			for _, category := range categories {
+				if category.ArticleCount > 0 {
+					filtered = append(filtered, category)
+				}
+			}
+			categories = filtered
+		}
+		c.JSON(http.StatusOK, categories)
+		return
+	}
+
 	categories, err := cc.service.GetCategories()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
@@ -26,6 +49,16 @@ func (cc *CategoryController) GetCategories(c *gin.Context) {
 	c.JSON(http.StatusOK, categories)
 }
 
+// GetCategoryTree 获取分类树（含子孙分类的文章数）
+func (cc *CategoryController) GetCategoryTree(c *gin.Context) {
+	tree, err := cc.service.GetCategoryTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category tree"})
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
 func (cc *CategoryController) GetCategory(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	category, err := cc.service.GetCategory(uint(id))
@@ -45,6 +78,13 @@ func (cc *CategoryController) CreateCategory(c *gin.Context) {
 
 	category, err := cc.service.CreateCategory(&input)
 	if err != nil {
+		if isCategoryParentError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if renderSlugError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
 		return
 	}
@@ -55,13 +95,28 @@ func (cc *CategoryController) CreateCategory(c *gin.Context) {
 func (cc *CategoryController) UpdateCategory(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	var input models.Category
-	if err := c.ShouldBindJSON(&input); err != nil {
+	if err := c.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 只有显式传入 parent_id（包括 null）、seo 时才修改父分类和 SEO 信息
+	var fields map[string]json.RawMessage
+	if err := c.ShouldBindBodyWith(&fields, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	_, parentSet := fields["parent_id"]
+	_, seoSet := fields["seo"]
 
-	category, err := cc.service.UpdateCategory(uint(id), &input)
+	category, err := cc.service.UpdateCategory(uint(id), &input, services.CategoryFields{Parent: parentSet, SEO: seoSet})
 	if err != nil {
+		if isCategoryParentError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if renderSlugError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
 		return
 	}
@@ -71,10 +126,52 @@ func (cc *CategoryController) UpdateCategory(c *gin.Context) {
 
 func (cc *CategoryController) DeleteCategory(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	if err := cc.service.DeleteCategory(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+
+	var reassignTo *uint
+	if raw := c.Query("reassign_to"); raw != "" {
+		target, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reassign_to"})
+			return
+		}
+		value := uint(target)
+		reassignTo = &value
+	}
+
+	moved, err := cc.service.DeleteCategory(uint(id), reassignTo)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		case errors.Is(err, services.ErrCategoryHasArticles):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrCategoryReassignTarget):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Category deleted successfully",
+		"moved_articles": moved,
+	})
+}
+
+func isCategoryParentError(err error) bool {
+	return errors.Is(err, services.ErrCategoryParentNotFound) || errors.Is(err, services.ErrCategoryCycle)
+}
+
+// renderSlugError 处理自定义 slug 不合法或已被占用的错误，已写入响应时返回 true
+func renderSlugError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrSlugInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
 }
@@ -3,10 +3,12 @@ package controllers
 import (
 	"blog-system/models"
 	"blog-system/services"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type CommentController struct {
@@ -17,7 +19,7 @@ func NewCommentController(service services.CommentService) *CommentController {
 	return &CommentController{service: service}
 }
 
-// GetComments 获取评论列表
+// GetComments 获取已审核评论列表（公开字段）
 func (cc *CommentController) GetComments(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
@@ -29,47 +31,289 @@ func (cc *CommentController) GetComments(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"comments": comments,
-		"total":    total,
-		"page":     page,
+		"comments":  comments,
+		"total":     total,
+		"page":      page,
 		"page_size": pageSize,
 	})
 }
 
-// CreateComment 创建评论
-func (cc *CommentController) CreateComment(c *gin.Context) {
-	var input struct {
-		ArticleID uint   `json:"article_id" binding:"required"`
-		Content   string `json:"content" binding:"required"`
-		Author    string `json:"author" binding:"required"`
-		Email     string `json:"email"`
-		Website   string `json:"website"`
-		ParentID  *uint  `json:"parent_id"`
+// GetAllComments 获取全部评论（管理端，包含邮箱和 IP）
+func (cc *CommentController) GetAllComments(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	comments, total, err := cc.service.GetAllComments(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments":  comments,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetTargetComments 返回获取评论对象已审核评论嵌套树的处理函数，按顶层评论分页；
+// param 为路由中对象 ID（或 slug）的参数名，留言板为空
+func (cc *CommentController) GetTargetComments(targetType, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 20
+		}
+
+		sortBy := c.DefaultQuery("sort", services.CommentSortNewest)
+		switch sortBy {
+		case services.CommentSortOldest, services.CommentSortNewest, services.CommentSortReplies:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of oldest, newest, replies"})
+			return
+		}
+
+		target, err := cc.service.ResolveTarget(targetType, c.Param(param))
+		if err != nil {
+			if !renderCommentTargetError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+			}
+			return
+		}
+
+		threads, total, err := cc.service.GetCommentTree(target, services.CommentTreeOptions{
+			Page:     page,
+			PageSize: pageSize,
+			Sort:     sortBy,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"target":    target,
+			"comments":  threads,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+			"sort":      sortBy,
+		})
+	}
+}
+
+type commentInput struct {
+	// 评论对象，只传 article_id 时视为文章评论（兼容旧客户端）
+	TargetType string `json:"target_type"`
+	TargetID   uint   `json:"target_id"`
+	ArticleID  uint   `json:"article_id"`
+	Content    string `json:"content" binding:"required"`
+	Author     string `json:"author" binding:"required"`
+	Email      string `json:"email"`
+	Website    string `json:"website"`
+	ParentID   *uint  `json:"parent_id"`
+	// 反垃圾字段：honeypot 为前端隐藏的输入框，form_token 为渲染表单时从 GET /comments/form-token 取得的一次性令牌
+	Honeypot  string `json:"honeypot"`
+	FormToken string `json:"form_token"`
+}
+
+// GetFormToken 签发评论表单令牌，前端在渲染评论表单时按 ?target_type=&target_id= 获取并随提交带回；
+// 令牌只对该评论对象有效，且只能发表一条评论
+func (cc *CommentController) GetFormToken(c *gin.Context) {
+	targetType, key := c.Query("target_type"), c.Query("target_id")
+	if targetType == "" && c.Query("article_id") != "" {
+		targetType, key = models.CommentTargetArticle, c.Query("article_id")
+	}
+	if targetType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_type is required"})
+		return
 	}
 
+	target, err := cc.service.ResolveTarget(targetType, key)
+	if err != nil {
+		if !renderCommentTargetError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue form token"})
+		}
+		return
+	}
+	token, err := services.IssueCommentFormToken(target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue form token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"form_token": token,
+		"expires_in": int(services.CommentFormTokenTTL.Seconds()),
+	})
+}
+
+// CreateComment 创建评论，评论对象由请求体的 target_type/target_id 指定
+func (cc *CommentController) CreateComment(c *gin.Context) {
+	var input commentInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if input.TargetType == "" && input.ArticleID != 0 {
+		input.TargetType = models.CommentTargetArticle
+		input.TargetID = input.ArticleID
+	}
+	if input.TargetType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_type is required"})
+		return
+	}
+	cc.createComment(c, &input)
+}
+
+// CreateTargetComment 返回在指定类型的评论对象下发表评论的处理函数，对象取自路由参数
+func (cc *CommentController) CreateTargetComment(targetType, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input commentInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		target, err := cc.service.ResolveTarget(targetType, c.Param(param))
+		if err != nil {
+			if !renderCommentTargetError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+			}
+			return
+		}
+		input.TargetType = target.Type
+		input.TargetID = target.ID
+		cc.createComment(c, &input)
+	}
+}
 
+func (cc *CommentController) createComment(c *gin.Context, input *commentInput) {
 	comment := &models.Comment{
-		ArticleID: input.ArticleID,
-		Content:   input.Content,
-		Author:    input.Author,
-		Email:     input.Email,
-		Website:   input.Website,
-		ParentID:  input.ParentID,
-		IP:        c.ClientIP(),
-		Status:    "pending",
+		TargetType: input.TargetType,
+		TargetID:   input.TargetID,
+		Content:    input.Content,
+		Author:     input.Author,
+		Email:      input.Email,
+		Website:    input.Website,
+		ParentID:   input.ParentID,
+		IP:         c.ClientIP(),
+		Status:     models.CommentStatusPending,
+	}
+
+	meta := services.CommentMeta{
+		Honeypot:  input.Honeypot,
+		FormToken: input.FormToken,
+		UserAgent: c.Request.UserAgent(),
+		Referrer:  c.Request.Referer(),
+	}
+
+	createdComment, editToken, err := cc.service.CreateComment(comment, meta)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCommentsClosed), errors.Is(err, services.ErrEmailNotVerified):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, services.ErrCommentFormTokenUsed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case isCommentContentError(err), errors.Is(err, services.ErrInvalidCommentParent):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !renderCommentTargetError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		}
+		return
 	}
 
-	createdComment, err := cc.service.CreateComment(comment)
+	// edit_token 只在这里返回一次，前端应保存在本地用于修改或撤回评论
+	c.JSON(http.StatusCreated, gin.H{
+		"comment":         services.NewPublicComment(*createdComment),
+		"status":          createdComment.Status,
+		"edit_token":      editToken,
+		"edit_expires_at": services.CommentEditDeadline(createdComment),
+	})
+}
+
+// EditComment 评论者凭 ?token= 编辑令牌修改自己的评论
+func (cc *CommentController) EditComment(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var input struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := cc.service.EditOwnComment(uint(id), c.Query("token"), input.Content, c.ClientIP())
+	if err != nil {
+		if !renderCommentOwnerError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comment": services.NewPublicComment(*comment),
+		"status":  comment.Status,
+	})
+}
+
+// GetCommentRevisions 查看评论被作者修改前的历史版本
+func (cc *CommentController) GetCommentRevisions(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	revisions, err := cc.service.GetCommentRevisions(uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment revisions"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, createdComment)
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// renderCommentTargetError 处理评论对象类型无效或不存在的错误，已处理时返回 true
+func renderCommentTargetError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrInvalidCommentTarget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment target not found"})
+	default:
+		return false
+	}
+	return true
+}
+
+func isCommentContentError(err error) bool {
+	return errors.Is(err, services.ErrCommentEmpty) ||
+		errors.Is(err, services.ErrCommentTooLong) ||
+		errors.Is(err, services.ErrCommentTooManyLinks)
+}
+
+// renderCommentOwnerError 处理评论者修改/撤回评论时的错误，已处理时返回 true
+func renderCommentOwnerError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrCommentEditToken),
+		errors.Is(err, services.ErrCommentEditExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case isCommentContentError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+	default:
+		return false
+	}
+	return true
 }
 
 // UpdateCommentStatus 更新评论状态
@@ -86,6 +330,14 @@ func (cc *CommentController) UpdateCommentStatus(c *gin.Context) {
 
 	comment, err := cc.service.UpdateCommentStatus(uint(id), input.Status)
 	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCommentStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
 		return
 	}
@@ -93,9 +345,53 @@ func (cc *CommentController) UpdateCommentStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, comment)
 }
 
-// DeleteComment 删除评论
+// BulkModerateComments 批量通过、拒绝、标记垃圾或删除评论
+func (cc *CommentController) BulkModerateComments(c *gin.Context) {
+	var input struct {
+		IDs    []uint `json:"ids" binding:"required,min=1,max=500"`
+		Action string `json:"action" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	affected, err := cc.service.BulkModerate(input.IDs, input.Action)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBulkAction) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":   input.Action,
+		"affected": affected,
+	})
+}
+
+// DeleteComment 删除评论；带 ?token= 时为评论者凭编辑令牌撤回自己的评论，无需登录
 func (cc *CommentController) DeleteComment(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	if token := c.Query("token"); token != "" {
+		if err := cc.service.DeleteOwnComment(uint(id), token); err != nil {
+			if !renderCommentOwnerError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+		return
+	}
+
+	// 没有编辑令牌时按管理操作处理
+	if !services.HasPermission(c.GetString("role"), services.PermCommentModerate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied", "required": []string{services.PermCommentModerate}})
+		return
+	}
+
 	if err := cc.service.DeleteComment(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
 		return
@@ -116,9 +412,9 @@ func (cc *CommentController) GetPendingComments(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"comments": comments,
-		"total":    total,
-		"page":     page,
+		"comments":  comments,
+		"total":     total,
+		"page":      page,
 		"page_size": pageSize,
 	})
 }
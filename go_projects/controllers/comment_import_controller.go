@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"blog-system/config"
+	"blog-system/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentImportController struct {
+	service services.CommentImportService
+}
+
+func NewCommentImportController(service services.CommentImportService) *CommentImportController {
+	return &CommentImportController{service: service}
+}
+
+// ImportComments 导入 Disqus、Twikoo 或 Waline 的评论导出文件
+// 表单字段：source（disqus/twikoo/waline）、file；dry_run=true 时只返回匹配报告
+func (ic *CommentImportController) ImportComments(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	if file.Size > config.AppConfig.MaxUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File too large"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	dryRun := c.PostForm("dry_run") == "true" || c.Query("dry_run") == "true"
+	report, err := ic.service.Import(c.PostForm("source"), file.Filename, src, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidImportSource), errors.Is(err, services.ErrImportFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case report != nil:
+			// 部分评论已经写入，返回报告便于排查后重新导入（已导入的会被跳过）
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Import interrupted", "report": report})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import comments"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -15,18 +15,20 @@ type LabController struct {
 }
 
 type labResponse struct {
-	ID          uint                  `json:"id"`
-	Title       string                `json:"title"`
-	Slug        string                `json:"slug"`
-	Subtitle    string                `json:"subtitle"`
-	Badge       string                `json:"badge"`
-	BadgeColor  string                `json:"badge_color"`
-	Description string                `json:"description"`
-	Focus       string                `json:"focus"`
-	HeroImage   string                `json:"hero_image"`
-	Highlights  []models.LabHighlight `json:"highlights,omitempty"`
-	Content     string                `json:"content,omitempty"`
-	Resources   []models.LabResource  `json:"resources,omitempty"`
+	ID           uint                  `json:"id"`
+	Title        string                `json:"title"`
+	Slug         string                `json:"slug"`
+	Subtitle     string                `json:"subtitle"`
+	Badge        string                `json:"badge"`
+	BadgeColor   string                `json:"badge_color"`
+	Description  string                `json:"description"`
+	Focus        string                `json:"focus"`
+	HeroImage    string                `json:"hero_image"`
+	Highlights   []models.LabHighlight `json:"highlights,omitempty"`
+	Content      string                `json:"content,omitempty"`
+	Resources    []models.LabResource  `json:"resources,omitempty"`
+	SEO          *models.SEO           `json:"seo,omitempty"`
+	SEOEffective *models.SEO           `json:"seo_effective,omitempty"`
 }
 
 func NewLabController(labService services.LabService, articleService services.ArticleService) *LabController {
@@ -88,6 +90,8 @@ func mapLabToResponse(lab models.Lab, includeContent bool) labResponse {
 
 	if includeContent {
 		resp.Content = lab.Content
+		resp.SEO = &lab.SEO
+		resp.SEOEffective = lab.SEOEffective
 		if len(lab.ResourceLinks) > 0 {
 			var resources []models.LabResource
 			if err := json.Unmarshal(lab.ResourceLinks, &resources); err == nil {
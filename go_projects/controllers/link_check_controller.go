@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"blog-system/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LinkCheckController struct {
+	service services.LinkCheckerService
+}
+
+func NewLinkCheckController(service services.LinkCheckerService) *LinkCheckController {
+	return &LinkCheckController{service: service}
+}
+
+// CheckLinks 检查文章与实验室内容中的失效链接
+func (lc *LinkCheckController) CheckLinks(c *gin.Context) {
+	opts := services.LinkCheckOptions{
+		CheckExternal: c.Query("external") == "true",
+	}
+	if concurrency, err := strconv.Atoi(c.Query("concurrency")); err == nil {
+		opts.Concurrency = concurrency
+	}
+	if timeout, err := strconv.Atoi(c.Query("timeout")); err == nil {
+		opts.Timeout = time.Duration(timeout) * time.Second
+	}
+
+	if articleID := c.Query("article_id"); articleID != "" {
+		report, err := lc.service.CheckArticle(articleID, opts)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	reports, err := lc.service.CheckAll(opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check links"})
+		return
+	}
+
+	// 默认只返回存在失效链接的内容
+	if c.Query("all") != "true" {
+		filtered := make([]services.LinkReport, 0, len(reports))
+		for _, report := range reports {
+			if len(report.Broken) > 0 {
+				filtered = append(filtered, report)
+			}
+		}
+		reports = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"total":   len(reports),
+	})
+}
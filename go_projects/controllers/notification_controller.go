@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"blog-system/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	service services.NotificationService
+}
+
+func NewNotificationController(service services.NotificationService) *NotificationController {
+	return &NotificationController{service: service}
+}
+
+// ConfirmUnsubscribe 邮件中的退订链接只展示确认页面，用户点击按钮后才以 POST 退订，
+// 避免邮件安全扫描或浏览器预取链接时误退订
+func (nc *NotificationController) ConfirmUnsubscribe(c *gin.Context) {
+	if err := nc.service.VerifyUnsubscribe(c.Query("e"), c.Query("t")); err != nil {
+		renderUnsubscribePage(c, http.StatusBadRequest, services.UnsubscribePageInvalid)
+		return
+	}
+	renderUnsubscribePage(c, http.StatusOK, services.UnsubscribePageConfirm)
+}
+
+// Unsubscribe 退订邮件通知；来自确认页面的表单提交返回页面，邮件客户端的一键退订（RFC 8058）和 API 调用返回 JSON
+func (nc *NotificationController) Unsubscribe(c *gin.Context) {
+	wantsHTML := c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML
+	err := nc.service.Unsubscribe(c.Query("e"), c.Query("t"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnsubscribeToken) {
+			if wantsHTML {
+				renderUnsubscribePage(c, http.StatusBadRequest, services.UnsubscribePageInvalid)
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		return
+	}
+
+	if wantsHTML {
+		renderUnsubscribePage(c, http.StatusOK, services.UnsubscribePageDone)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You will no longer receive comment notifications"})
+}
+
+func renderUnsubscribePage(c *gin.Context, status int, state string) {
+	html, err := services.RenderUnsubscribePage(state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render page"})
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", []byte(html))
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"blog-system/config"
+	"blog-system/services"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// oauthStateCookie 发起第三方登录的浏览器保存 state，回调时比对
+const oauthStateCookie = "oauth_state"
+
+// OAuthProviders 已配置的第三方登录提供方
+func (ac *AuthController) OAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": ac.service.OAuthProviders()})
+}
+
+// OAuthLogin 跳转到提供方的授权页面
+func (ac *AuthController) OAuthLogin(c *gin.Context) {
+	authURL, state, err := ac.service.BeginOAuth(c.Param("provider"), nil)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	setOAuthStateCookie(c, state)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthLink 已登录用户关联新的第三方身份，返回授权地址由前端跳转
+func (ac *AuthController) OAuthLink(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	uid := userID.(uint)
+	authURL, state, err := ac.service.BeginOAuth(c.Param("provider"), &uid)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	// 与登录流程一样把 state 绑定到发起关联的浏览器，防止攻击者诱导他人完成对攻击者账号的关联
+	setOAuthStateCookie(c, state)
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// OAuthCallback 提供方回调，处理完成后跳回前端页面，结果放在 URL 片段中（不会发送到服务器或写入日志）
+func (ac *AuthController) OAuthCallback(c *gin.Context) {
+	boundState, _ := c.Cookie(oauthStateCookie)
+	c.SetCookie(oauthStateCookie, "", -1, "/api/auth/oauth", "", false, true)
+
+	fragment := url.Values{}
+	fragment.Set("provider", c.Param("provider"))
+	if c.Query("error") != "" {
+		// 用户在提供方拒绝授权等情况
+		fragment.Set("error", firstNonEmptyQuery(c, "error_description", "error"))
+		c.Redirect(http.StatusFound, services.OAuthFrontendURL()+"#"+fragment.Encode())
+		return
+	}
+
+	result, err := ac.service.CompleteOAuth(c.Param("provider"), c.Query("state"), boundState, c.Query("code"), sessionMeta(c))
+	switch {
+	case err != nil && isOAuthClientError(err):
+		fragment.Set("error", err.Error())
+	case err != nil:
+		fragment.Set("error", services.ErrOAuthFailed.Error())
+	case result.Linked:
+		fragment.Set("linked", "true")
+	case result.Challenge != nil:
+		fragment.Set("two_factor_required", "true")
+		fragment.Set("challenge_token", result.Challenge.ChallengeToken)
+		fragment.Set("expires_in", strconv.Itoa(result.Challenge.ExpiresIn))
+	default:
+		fragment.Set("token", result.Tokens.AccessToken)
+		fragment.Set("expires_in", strconv.Itoa(result.Tokens.ExpiresIn))
+		fragment.Set("refresh_token", result.Tokens.RefreshToken)
+		fragment.Set("refresh_expires_at", result.Tokens.RefreshExpiresAt.Format(time.RFC3339))
+		if result.Created {
+			fragment.Set("created", "true")
+		}
+	}
+	c.Redirect(http.StatusFound, services.OAuthFrontendURL()+"#"+fragment.Encode())
+}
+
+// ListIdentities 当前用户已关联的第三方身份
+func (ac *AuthController) ListIdentities(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	identities, err := ac.service.ListIdentities(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// UnlinkIdentity 取消关联第三方身份
+func (ac *AuthController) UnlinkIdentity(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity ID"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := ac.service.UnlinkIdentity(userID.(uint), uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		} else if errors.Is(err, services.ErrLastIdentity) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked"})
+}
+
+// setOAuthStateCookie 在发起浏览器中保存 state，回调时由 CompleteOAuth 比对
+func setOAuthStateCookie(c *gin.Context, state string) {
+	maxAge := config.AppConfig.OAuthStateTTLMinutes * 60
+	secure := c.Request.TLS != nil || strings.HasPrefix(config.AppConfig.SiteURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, maxAge, "/api/auth/oauth", "", secure, true)
+}
+
+func respondOAuthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOAuthProviderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOAuthFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start oauth login"})
+	}
+}
+
+// isOAuthClientError 可以直接展示给用户的错误
+func isOAuthClientError(err error) bool {
+	for _, target := range []error{
+		services.ErrOAuthProviderNotFound,
+		services.ErrInvalidOAuthState,
+		services.ErrOAuthFailed,
+		services.ErrOAuthEmailRequired,
+		services.ErrOAuthSignupDisabled,
+		services.ErrOAuthAccountUnverified,
+		services.ErrIdentityLinkedElsewhere,
+		services.ErrEmailNotVerified,
+		services.ErrAccountDisabled,
+		services.ErrPasswordResetRequired,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmptyQuery(c *gin.Context, keys ...string) string {
+	for _, key := range keys {
+		if v := c.Query(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
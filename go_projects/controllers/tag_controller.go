@@ -3,10 +3,12 @@ package controllers
 import (
 	"blog-system/models"
 	"blog-system/services"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type TagController struct {
@@ -18,6 +20,16 @@ func NewTagController(service services.TagService) *TagController {
 }
 
 func (tc *TagController) GetTags(c *gin.Context) {
+	if c.Query("with_stats") == "true" {
+		tags, err := tc.service.GetTagsWithStats()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+			return
+		}
+		c.JSON(http.StatusOK, tags)
+		return
+	}
+
 	tags, err := tc.service.GetTags()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
@@ -26,6 +38,36 @@ func (tc *TagController) GetTags(c *gin.Context) {
 	c.JSON(http.StatusOK, tags)
 }
 
+// SuggestTags 标签自动补全（支持拼音全拼与首字母）
+func (tc *TagController) SuggestTags(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	suggestions, err := tc.service.SuggestTags(c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suggest tags"})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// GetTagCloud 获取带权重分档的标签云
+func (tc *TagController) GetTagCloud(c *gin.Context) {
+	buckets, _ := strconv.Atoi(c.DefaultQuery("buckets", "5"))
+	minCount, _ := strconv.ParseInt(c.DefaultQuery("min_count", "1"), 10, 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "0"))
+
+	items, err := tc.service.GetTagCloud(services.TagCloudOptions{
+		Buckets:  buckets,
+		Scale:    c.DefaultQuery("scale", "log"),
+		MinCount: minCount,
+		Limit:    limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build tag cloud"})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
 func (tc *TagController) CreateTag(c *gin.Context) {
 	var input models.Tag
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -35,6 +77,13 @@ func (tc *TagController) CreateTag(c *gin.Context) {
 
 	tag, err := tc.service.CreateTag(&input)
 	if err != nil {
+		if errors.Is(err, services.ErrTagSlugTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if renderSlugError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
 		return
 	}
@@ -42,12 +91,160 @@ func (tc *TagController) CreateTag(c *gin.Context) {
 	c.JSON(http.StatusCreated, tag)
 }
 
+// UpdateTag 更新标签名称与 SEO 信息（旧 slug 自动保留为别名）
+func (tc *TagController) UpdateTag(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var input struct {
+		Name string     `json:"name" binding:"required"`
+		SEO  models.SEO `json:"seo"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := tc.service.UpdateTag(uint(id), &models.Tag{Name: input.Name, SEO: input.SEO})
+	if err != nil {
+		tc.renderRenameError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// RenameTag 重命名标签，可指定新的 slug
+func (tc *TagController) RenameTag(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var input struct {
+		Name string `json:"name" binding:"required"`
+		Slug string `json:"slug"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := tc.service.RenameTag(uint(id), input.Name, input.Slug)
+	if err != nil {
+		tc.renderRenameError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+func (tc *TagController) renderRenameError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTagSlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSlugInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
+	}
+}
+
 func (tc *TagController) DeleteTag(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	if err := tc.service.DeleteTag(uint(id)); err != nil {
+	affected, err := tc.service.DeleteTag(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Tag deleted successfully",
+		"affected_articles": affected,
+	})
+}
+
+// MergeTags 合并标签：来源标签的文章迁移到目标标签，来源标签变为别名
+func (tc *TagController) MergeTags(c *gin.Context) {
+	var input struct {
+		SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+		TargetID  uint   `json:"target_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := tc.service.MergeTags(input.SourceIDs, input.TargetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTagMergeInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTagMergeSourceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target tag not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge tags"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags merged successfully",
+		"tag":     tag,
+	})
+}
+
+// GetTagAliases 获取标签别名
+func (tc *TagController) GetTagAliases(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	aliases, err := tc.service.GetTagAliases(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	c.JSON(http.StatusOK, aliases)
+}
+
+// AddTagAlias 添加标签别名
+func (tc *TagController) AddTagAlias(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias, err := tc.service.AddTagAlias(uint(id), input.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTagSlugTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag alias"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// DeleteTagAlias 删除标签别名
+func (tc *TagController) DeleteTagAlias(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("alias_id"))
+	if err := tc.service.DeleteTagAlias(uint(id)); err != nil {
+		if errors.Is(err, services.ErrTagAliasNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag alias"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag alias deleted successfully"})
 }
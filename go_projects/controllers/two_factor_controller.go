@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"blog-system/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TwoFactorStatus 当前用户是否已启用两步验证及剩余恢复码数量
+func (ac *AuthController) TwoFactorStatus(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	enabled, remaining, err := ac.service.TwoFactorStatus(userID.(uint))
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to load two-factor status")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  enabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// TwoFactorSetup 生成新的密钥，需要再调用 confirm 提交验证码后才会生效
+func (ac *AuthController) TwoFactorSetup(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	setup, err := ac.service.SetupTwoFactor(userID.(uint))
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to start two-factor setup")
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// TwoFactorConfirm 提交验证器中的验证码启用两步验证，返回只显示一次的恢复码
+func (ac *AuthController) TwoFactorConfirm(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	codes, tokens, err := ac.service.ConfirmTwoFactor(userID.(uint), input.Code, sessionMeta(c))
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Two-factor authentication enabled",
+		"recovery_codes":     codes,
+		"token":              tokens.AccessToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	})
+}
+
+// TwoFactorDisable 关闭两步验证，需要当前密码和验证码（或恢复码）；其他会话全部退出，当前客户端换发新令牌
+func (ac *AuthController) TwoFactorDisable(c *gin.Context) {
+	var input struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	tokens, err := ac.service.DisableTwoFactor(userID.(uint), input.Password, input.Code, sessionMeta(c))
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Two-factor authentication disabled",
+		"token":              tokens.AccessToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	})
+}
+
+// RegenerateRecoveryCodes 生成新的恢复码，旧恢复码全部作废
+func (ac *AuthController) RegenerateRecoveryCodes(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	codes, err := ac.service.RegenerateRecoveryCodes(userID.(uint), input.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// VerifyTwoFactor 两步登录的第二步，提交挑战令牌和验证码（或恢复码）
+func (ac *AuthController) VerifyTwoFactor(c *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, user, err := ac.service.VerifyTwoFactorLogin(input.ChallengeToken, input.Code, sessionMeta(c))
+	if err != nil {
+		respondTwoFactorError(c, err, "Failed to verify two-factor code")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Login successful",
+		"token":              tokens.AccessToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+			"role":     user.Role,
+			"avatar":   user.Avatar,
+		},
+	})
+}
+
+func respondTwoFactorError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode), errors.Is(err, services.ErrInvalidChallenge):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWrongPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrTwoFactorSetupRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTooManyRequests):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAccountDisabled), errors.Is(err, services.ErrPasswordResetRequired),
+		errors.Is(err, services.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"blog-system/repositories"
+	"blog-system/services"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UserController 用户与角色管理
+type UserController struct {
+	service        services.UserService
+	articleService services.ArticleService
+}
+
+func NewUserController(service services.UserService, articleService services.ArticleService) *UserController {
+	return &UserController{service: service, articleService: articleService}
+}
+
+// ListUsers 用户列表，支持按用户名或邮箱搜索、按角色和状态筛选
+func (uc *UserController) ListUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filters := make(map[string]interface{})
+	if search := c.Query("search"); search != "" {
+		filters["search"] = search
+	}
+	if role := c.Query("role"); role != "" {
+		filters["role"] = role
+	}
+	if status := c.Query("status"); status != "" {
+		if status != "active" && status != "disabled" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or disabled"})
+			return
+		}
+		filters["status"] = status
+	}
+
+	users, total, err := uc.service.ListUsers(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(users))
+	for i := range users {
+		items = append(items, adminUserJSON(&users[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"users":     items,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetUser 用户详情，包括文章和评论数量
+func (uc *UserController) GetUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := uc.service.GetUserStats(id)
+	if err != nil {
+		respondUserError(c, err, "Failed to fetch user")
+		return
+	}
+
+	c.JSON(http.StatusOK, adminUserJSON(user))
+}
+
+// GetUserArticles 用户的全部文章，包括草稿
+func (uc *UserController) GetUserArticles(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	if _, err := uc.service.GetUser(id); err != nil {
+		respondUserError(c, err, "Failed to fetch user")
+		return
+	}
+
+	filters := map[string]interface{}{"author_id": id}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	articles, total, page, pageSize, err := uc.articleService.GetArticles(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"articles":  articles,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// DisableUser 禁用或临时封禁账号
+func (uc *UserController) DisableUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string     `json:"reason"`
+		Until  *time.Time `json:"until"` // 为空表示一直禁用到手动启用
+	}
+	// 请求体可以省略
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID, _ := c.Get("user_id")
+	if _, err := uc.service.DisableUser(actorID.(uint), id, input.Reason, input.Until); err != nil {
+		respondUserError(c, err, "Failed to disable user")
+		return
+	}
+	uc.GetUser(c)
+}
+
+// EnableUser 解除禁用
+func (uc *UserController) EnableUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	actorID, _ := c.Get("user_id")
+	if _, err := uc.service.EnableUser(actorID.(uint), id); err != nil {
+		respondUserError(c, err, "Failed to enable user")
+		return
+	}
+	uc.GetUser(c)
+}
+
+// ForcePasswordReset 强制重置密码：原密码失效、所有设备退出，用户通过邮件中的链接设置新密码
+func (uc *UserController) ForcePasswordReset(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	actorID, _ := c.Get("user_id")
+	resetURL, err := uc.service.ForcePasswordReset(actorID.(uint), id, c.ClientIP())
+	if err != nil {
+		respondUserError(c, err, "Failed to reset password")
+		return
+	}
+
+	if resetURL != "" {
+		// 未配置邮件（只有管理员会拿到链接），由管理员把链接转交给用户
+		c.JSON(http.StatusOK, gin.H{"message": "Password has been reset", "reset_url": resetURL})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset and a reset link has been sent to the user"})
+}
+
+// GetRoles 全部角色及其权限
+func (uc *UserController) GetRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"roles":       services.Roles(),
+		"permissions": services.AllPermissions,
+	})
+}
+
+// AssignRole 修改用户角色
+func (uc *UserController) AssignRole(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID, _ := c.Get("user_id")
+	user, err := uc.service.AssignRole(actorID.(uint), id, input.Role)
+	if err != nil {
+		respondUserError(c, err, "Failed to assign role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":          user.ID,
+		"username":    user.Username,
+		"role":        user.Role,
+		"permissions": services.RolePermissions(user.Role),
+	})
+}
+
+func userIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondUserError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrInvalidBanUntil), errors.Is(err, services.ErrCannotDisableSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAdminOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMailNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func adminUserJSON(stats *repositories.UserStats) gin.H {
+	user := &stats.User
+	return gin.H{
+		"id":                 user.ID,
+		"username":           user.Username,
+		"email":              user.Email,
+		"avatar":             user.Avatar,
+		"role":               user.Role,
+		"email_verified_at":  user.EmailVerifiedAt,
+		"two_factor_enabled": user.TOTPEnabledAt != nil,
+		"disabled":           user.IsDisabled(time.Now()),
+		"disabled_at":        user.DisabledAt,
+		"disabled_until":     user.DisabledUntil,
+		"disabled_reason":    user.DisabledReason,
+		"article_count":      stats.ArticleCount,
+		"comment_count":      stats.CommentCount,
+		"created_at":         user.CreatedAt,
+	}
+}
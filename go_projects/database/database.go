@@ -3,6 +3,7 @@ package database
 import (
 	"blog-system/config"
 	"blog-system/models"
+	"blog-system/utils"
 	"log"
 
 	"gorm.io/driver/mysql"
@@ -30,24 +31,38 @@ func InitDB() {
 
 	DB, err = gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// 唯一索引冲突统一转换为 gorm.ErrDuplicatedKey，便于并发写入时返回 409
+		TranslateError: true,
 	})
 
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// 邮箱验证上线前注册的用户视为已验证，需要在迁移加列之前判断
+	verifyExistingUsers := DB.Migrator().HasTable(&models.User{}) && !DB.Migrator().HasColumn(&models.User{}, "email_verified_at")
+
 	// 自动迁移
 	err = DB.AutoMigrate(
 		&models.User{},
 		&models.Article{},
 		&models.Category{},
 		&models.Tag{},
+		&models.TagAlias{},
 		&models.Comment{},
+		&models.CommentRevision{},
+		&models.CommentFormNonce{},
 		&models.Music{},
 		&models.Playlist{},
 		&models.Link{},
 		&models.SiteConfig{},
 		&models.Lab{},
+		&models.EmailUnsubscribe{},
+		&models.RefreshToken{},
+		&models.PasswordReset{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.OAuthState{},
 	)
 
 	if err != nil {
@@ -55,8 +70,86 @@ func InitDB() {
 	}
 
 	log.Println("Database connected and migrated successfully")
-	
+
+	// 旧版评论只关联文章，迁移为通用的评论对象
+	migrateCommentTargets()
+
+	// 引入角色权限前的普通用户统一为 user，按是否写过文章拆分为作者和读者
+	migrateLegacyRoles()
+
+	if verifyExistingUsers {
+		if err := DB.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error; err != nil {
+			log.Fatal("Failed to mark existing users as verified:", err)
+		}
+	}
+
+	// 为升级前的评论补全邮箱哈希
+	backfillCommentEmailHashes()
+	backfillCommentHTML()
+
 	// 填充初始数据
 	SeedDatabase()
 }
 
+func backfillCommentEmailHashes() {
+	var comments []models.Comment
+	DB.Select("id", "email").
+		Where("email <> '' AND (email_hash IS NULL OR email_hash = '')").
+		Find(&comments)
+	for _, comment := range comments {
+		DB.Model(&comment).UpdateColumn("email_hash", utils.EmailHash(comment.Email))
+	}
+}
+
+// migrateLegacyRoles 重复执行无副作用：迁移后不再有 user 角色
+func migrateLegacyRoles() {
+	err := DB.Exec("UPDATE users SET role = ? WHERE role = ? AND id IN (SELECT DISTINCT author_id FROM articles)",
+		models.RoleAuthor, "user").Error
+	if err == nil {
+		err = DB.Exec("UPDATE users SET role = ? WHERE role = ? OR role = '' OR role IS NULL",
+			models.RoleReader, "user").Error
+	}
+	if err != nil {
+		log.Fatal("Failed to migrate user roles:", err)
+	}
+}
+
+// backfillCommentHTML 为支持 Markdown 之前的评论生成 HTML
+func backfillCommentHTML() {
+	var comments []models.Comment
+	DB.Select("id", "content").
+		Where("content_html IS NULL OR content_html = ''").
+		Find(&comments)
+	for _, comment := range comments {
+		html, _, err := utils.RenderCommentMarkdown(comment.Content)
+		if err != nil {
+			continue
+		}
+		DB.Model(&comment).UpdateColumn("content_html", html)
+	}
+}
+
+// migrateCommentTargets 将 comments.article_id 转换为 target_type/target_id 后删除旧列和外键
+func migrateCommentTargets() {
+	migrator := DB.Migrator()
+	if !migrator.HasColumn(&models.Comment{}, "article_id") {
+		return
+	}
+
+	err := DB.Exec("UPDATE comments SET target_type = ?, target_id = article_id WHERE article_id IS NOT NULL",
+		models.CommentTargetArticle).Error
+	if err != nil {
+		log.Fatal("Failed to migrate comment targets:", err)
+	}
+	for _, name := range []string{"fk_articles_comments", "fk_comments_article"} {
+		if migrator.HasConstraint(&models.Comment{}, name) {
+			if err := migrator.DropConstraint(&models.Comment{}, name); err != nil {
+				log.Fatal("Failed to drop comment constraint:", err)
+			}
+		}
+	}
+	if err := migrator.DropColumn(&models.Comment{}, "article_id"); err != nil {
+		log.Fatal("Failed to drop comments.article_id:", err)
+	}
+	log.Println("Migrated article comments to comment targets")
+}
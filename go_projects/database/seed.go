@@ -1,4 +1,4 @@
-﻿package database
+package database
 
 import (
 	"blog-system/models"
@@ -28,6 +28,7 @@ func SeedDatabase() {
 		}
 	} else {
 		log.Println("Seeding database...")
+		verifiedAt := time.Now()
 
 		adminPassword, err := utils.HashPassword("admin123")
 		if err != nil {
@@ -35,12 +36,13 @@ func SeedDatabase() {
 			return
 		}
 		admin = models.User{
-			Username: "admin",
-			Email:    "admin@example.com",
-			Password: adminPassword,
-			Role:     "admin",
-			Bio:      "系统管理员，负责站点配置。",
-			Avatar:   "",
+			Username:        "admin",
+			Email:           "admin@example.com",
+			Password:        adminPassword,
+			Role:            models.RoleAdmin,
+			Bio:             "系统管理员，负责站点配置。",
+			Avatar:          "",
+			EmailVerifiedAt: &verifiedAt,
 		}
 		if err := DB.Create(&admin).Error; err != nil {
 			log.Printf("Error creating admin user: %v", err)
@@ -54,12 +56,13 @@ func SeedDatabase() {
 			return
 		}
 		user = models.User{
-			Username: "user",
-			Email:    "user@example.com",
-			Password: userPassword,
-			Role:     "user",
-			Bio:      "普通用户，用于演示评论功能。",
-			Avatar:   "",
+			Username:        "user",
+			Email:           "user@example.com",
+			Password:        userPassword,
+			Role:            models.RoleReader,
+			Bio:             "普通用户，用于演示评论功能。",
+			Avatar:          "",
+			EmailVerifiedAt: &verifiedAt,
 		}
 		if err := DB.Create(&user).Error; err != nil {
 			log.Printf("Error creating user: %v", err)
@@ -1,15 +1,25 @@
 package middleware
 
 import (
-	_ "blog-system/config"
+	"blog-system/models"
+	"blog-system/repositories"
 	"blog-system/utils"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errTokenRevoked    = errors.New("token has been revoked")
+	errAccountDisabled = errors.New("account has been disabled")
 )
 
 func AuthMiddleware() gin.HandlerFunc {
+	users := repositories.NewUserRepository()
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -33,21 +43,80 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
+		user, err := loadTokenUser(users, claims)
+		if err != nil {
+			switch {
+			case errors.Is(err, errTokenRevoked):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			case errors.Is(err, errAccountDisabled):
+				c.JSON(http.StatusForbidden, gin.H{"error": "This account has been disabled"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("role", user.Role)
+		c.Set("email_verified", user.EmailVerifiedAt != nil)
+		c.Set("two_factor_enabled", user.TOTPEnabledAt != nil)
 		c.Next()
 	}
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// loadTokenUser 按令牌加载用户。令牌版本落后说明会话已被吊销；角色和禁用状态以数据库为准，
+// 降级、禁用立即生效，未过期的令牌也无法继续使用
+func loadTokenUser(users repositories.UserRepository, claims *utils.Claims) (*models.User, error) {
+	user, err := users.FindByID(claims.UserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err != nil || user.TokenVersion != claims.Version {
+		return nil, errTokenRevoked
+	}
+	if user.IsDisabled(time.Now()) {
+		return nil, errAccountDisabled
+	}
+	return user, nil
+}
+
+// bearerUser 供匿名接口识别已登录用户，与 AuthMiddleware 做相同的数据库校验；
+// 没有令牌、令牌无效或已吊销、账号已禁用或需要重置密码时返回 nil
+func bearerUser(c *gin.Context, users repositories.UserRepository) *models.User {
+	claims := bearerClaims(c)
+	if claims == nil {
+		return nil
+	}
+	user, err := loadTokenUser(users, claims)
+	if err != nil || user.PasswordResetRequired {
+		return nil
+	}
+	return user
+}
+
+// bearerClaims 解析请求头中的访问令牌，没有或无效时返回 nil；供匿名接口识别已登录用户
+func bearerClaims(c *gin.Context) *utils.Claims {
+	parts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil
+	}
+	claims, err := utils.ValidateToken(parts[1])
+	if err != nil {
+		return nil
+	}
+	return claims
+}
+
+// AuthUnlessQuery 请求带有指定查询参数时跳过登录校验，由处理函数自行验证（如评论的编辑令牌）
+func AuthUnlessQuery(param string) gin.HandlerFunc {
+	auth := AuthMiddleware()
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
+		if c.Query(param) != "" {
+			c.Next()
 			return
 		}
-		c.Next()
+		auth(c)
 	}
 }
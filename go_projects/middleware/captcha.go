@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"blog-system/repositories"
+	"blog-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CaptchaMiddleware 要求匿名请求通过 X-Captcha-Challenge、X-Captcha-Nonce 请求头附带工作量证明；
+// 校验失败时响应中附带一道新题目，客户端求解后重试即可。
+// 已登录且通过与 AuthMiddleware 相同校验的用户不受影响；注册接口始终要求验证
+func CaptchaMiddleware(captcha services.CaptchaService, scope string) gin.HandlerFunc {
+	users := repositories.NewUserRepository()
+	return func(c *gin.Context) {
+		if !captcha.Enabled() {
+			c.Next()
+			return
+		}
+		if scope != services.CaptchaScopeRegister && bearerUser(c, users) != nil {
+			c.Next()
+			return
+		}
+
+		err := captcha.Verify(scope, c.GetHeader("X-Captcha-Challenge"), c.GetHeader("X-Captcha-Nonce"))
+		if err == nil {
+			c.Next()
+			return
+		}
+
+		body := gin.H{"error": err.Error()}
+		if challenge, issueErr := captcha.Issue(scope, c.ClientIP()); issueErr == nil {
+			body["captcha"] = challenge
+		}
+		c.JSON(http.StatusForbidden, body)
+		c.Abort()
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"blog-system/config"
+	"blog-system/database"
+	"blog-system/models"
+	"blog-system/services"
+	"blog-system/utils"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupTestDB 在临时目录中创建 SQLite 数据库，完成迁移和初始数据（admin/user 两个账号）
+func setupTestDB(t *testing.T, cfg config.Config) {
+	t.Helper()
+	cfg.DBType = "sqlite"
+	cfg.DBName = filepath.Join(t.TempDir(), "test.db")
+	cfg.JWTSecret = "test-secret"
+	cfg.JWTAccessTTLMinutes = 15
+	cfg.RBACDefaultRole = models.RoleReader
+	config.AppConfig = &cfg
+	database.InitDB()
+	t.Cleanup(func() {
+		if db, err := database.DB.DB(); err == nil {
+			db.Close()
+		}
+	})
+}
+
+func TestCaptchaMiddlewareBypass(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		disabled bool // 关闭验证码
+		scope    string
+		token    bool                    // 是否带上 seed 用户的访问令牌
+		prepare  func(user *models.User) // 签发令牌后修改数据库中的用户
+		want     int
+	}{
+		{name: "captcha disabled", disabled: true, scope: services.CaptchaScopeComment, want: http.StatusOK},
+		{name: "anonymous request", scope: services.CaptchaScopeComment, want: http.StatusForbidden},
+		{name: "active session", scope: services.CaptchaScopeComment, token: true, want: http.StatusOK},
+		{name: "active session on register", scope: services.CaptchaScopeRegister, token: true, want: http.StatusForbidden},
+		{
+			name: "revoked session", scope: services.CaptchaScopeComment, token: true,
+			prepare: func(user *models.User) { user.TokenVersion++ },
+			want:    http.StatusForbidden,
+		},
+		{
+			name: "disabled account", scope: services.CaptchaScopeLike, token: true,
+			prepare: func(user *models.User) {
+				now := time.Now()
+				user.DisabledAt = &now
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "password reset required", scope: services.CaptchaScopeComment, token: true,
+			prepare: func(user *models.User) { user.PasswordResetRequired = true },
+			want:    http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t, config.Config{
+				CaptchaEnabled:           !tt.disabled,
+				CaptchaDifficulty:        8,
+				CaptchaMaxDifficulty:     8,
+				CaptchaTTLSeconds:        300,
+				CaptchaRateWindowSeconds: 600,
+				CaptchaRateThreshold:     10,
+			})
+
+			var user models.User
+			if err := database.DB.Where("username = ?", "user").First(&user).Error; err != nil {
+				t.Fatal(err)
+			}
+			token, err := utils.GenerateToken(user.ID, user.Username, user.Role, user.TokenVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prepare != nil {
+				tt.prepare(&user)
+				if err := database.DB.Save(&user).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			router := gin.New()
+			router.POST("/", CaptchaMiddleware(services.NewCaptchaService(), tt.scope), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.token {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 要求当前用户的角色拥有全部指定权限，需放在 AuthMiddleware 之后
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if !services.HasPermission(role, perms...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied", "required": perms})
+			c.Abort()
+			return
+		}
+		// 开启 auth.require_admin_2fa 后，管理员需先在 /api/auth/2fa 完成两步验证设置
+		if role == models.RoleAdmin && config.AppConfig.AuthRequireAdmin2FA && !c.GetBool("two_factor_enabled") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for admin accounts"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -6,25 +6,33 @@ import (
 
 // Article 文章模型
 type Article struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Title       string    `json:"title" gorm:"type:varchar(255);not null"`
-	Slug        string    `json:"slug" gorm:"type:varchar(255);uniqueIndex;not null"`
-	Content     string    `json:"content" gorm:"type:longtext;not null"`
-	Excerpt     string    `json:"excerpt" gorm:"type:text"`
-	CoverImage  string    `json:"cover_image" gorm:"type:varchar(500)"`
-	Views       int       `json:"views" gorm:"default:0"`
-	Likes       int       `json:"likes" gorm:"default:0"`
-	Status      string    `json:"status" gorm:"type:varchar(20);default:draft"` // draft, published
-	IsTop       bool      `json:"is_top" gorm:"default:false"`
-	PublishedAt *time.Time `json:"published_at"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	Title      string `json:"title" gorm:"type:varchar(255);not null"`
+	Slug       string `json:"slug" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Content    string `json:"content" gorm:"type:longtext;not null"`
+	Excerpt    string `json:"excerpt" gorm:"type:text"`
+	CoverImage string `json:"cover_image" gorm:"type:varchar(500)"`
+	Views      int    `json:"views" gorm:"default:0"`
+	Likes      int    `json:"likes" gorm:"default:0"`
+	Status     string `json:"status" gorm:"type:varchar(20);default:draft"` // draft, published
+	IsTop      bool   `json:"is_top" gorm:"default:false"`
+	// 评论设置：open、moderated、closed；CommentAutoCloseDays > 0 时发布该天数后自动关闭评论，0 表示使用站点默认值
+	CommentMode          string     `json:"comment_mode" gorm:"type:varchar(20);default:open"`
+	CommentAutoCloseDays int        `json:"comment_auto_close_days" gorm:"default:0"`
+	PublishedAt          *time.Time `json:"published_at"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	SEO                  SEO        `json:"seo" gorm:"embedded;embeddedPrefix:seo_"`
+	SEOEffective         *SEO       `json:"seo_effective,omitempty" gorm:"-"` // 填充默认值后的 SEO，仅用于输出，不落库
+
 	// 关联
 	AuthorID   uint      `json:"author_id"`
 	Author     User      `json:"author" gorm:"foreignKey:AuthorID"`
 	CategoryID uint      `json:"category_id"`
 	Category   Category  `json:"category" gorm:"foreignKey:CategoryID"`
 	Tags       []Tag     `json:"tags" gorm:"many2many:article_tags;"`
-	Comments   []Comment `json:"comments" gorm:"foreignKey:ArticleID"`
+	Comments   []Comment `json:"comments,omitempty" gorm:"polymorphic:Target;polymorphicValue:article"`
+
+	// 分类路径（从根分类到当前分类），不落库
+	Breadcrumbs []CategoryCrumb `json:"breadcrumbs,omitempty" gorm:"-"`
 }
@@ -6,12 +6,29 @@ import (
 
 // Category 分类模型
 type Category struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"type:varchar(100);uniqueIndex;not null"`
-	Slug        string    `json:"slug" gorm:"type:varchar(100);uniqueIndex;not null"`
-	Description string    `json:"description" gorm:"type:text"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Name         string    `json:"name" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Slug         string    `json:"slug" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Description  string    `json:"description" gorm:"type:text"`
+	ParentID     *uint     `json:"parent_id" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	SEO          SEO       `json:"seo" gorm:"embedded;embeddedPrefix:seo_"`
+	SEOEffective *SEO      `json:"seo_effective,omitempty" gorm:"-"` // 填充默认值后的 SEO，仅用于输出，不落库
+
 	Articles []Article `json:"articles" gorm:"foreignKey:CategoryID"`
 }
+
+// CategoryCrumb 分类面包屑节点
+type CategoryCrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// CategoryWithStats 带文章统计的分类
+type CategoryWithStats struct {
+	Category
+	ArticleCount int64      `json:"article_count"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
@@ -1,24 +1,120 @@
 package models
 
 import (
+	"blog-system/utils"
 	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 评论状态
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusRejected = "rejected"
+	CommentStatusSpam     = "spam"
+)
+
+// ValidCommentStatus 判断是否为合法的评论状态
+func ValidCommentStatus(status string) bool {
+	switch status {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusRejected, CommentStatusSpam:
+		return true
+	}
+	return false
+}
+
+// 文章评论模式
+const (
+	CommentModeOpen      = "open"      // 按反垃圾结果和可信评论者自动处理
+	CommentModeModerated = "moderated" // 所有评论都需人工审核
+	CommentModeClosed    = "closed"    // 不再接受新评论
+)
+
+// ValidCommentMode 判断是否为合法的文章评论模式
+func ValidCommentMode(mode string) bool {
+	switch mode {
+	case CommentModeOpen, CommentModeModerated, CommentModeClosed:
+		return true
+	}
+	return false
+}
+
+// 评论对象类型
+const (
+	CommentTargetArticle   = "article"
+	CommentTargetLab       = "lab"
+	CommentTargetMusic     = "music"
+	CommentTargetPlaylist  = "playlist"
+	CommentTargetGuestbook = "guestbook" // 站点留言板，TargetID 固定为 0
 )
 
+// ValidCommentTarget 判断是否为可评论的对象类型
+func ValidCommentTarget(targetType string) bool {
+	switch targetType {
+	case CommentTargetArticle, CommentTargetLab, CommentTargetMusic, CommentTargetPlaylist, CommentTargetGuestbook:
+		return true
+	}
+	return false
+}
+
 // Comment 评论模型
 type Comment struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Content   string    `json:"content" gorm:"type:text;not null"`
+	// ContentHTML 创建时由 Content 的 Markdown 渲染并净化的 HTML
+	ContentHTML string  `json:"content_html" gorm:"type:text"`
 	Author    string    `json:"author" gorm:"type:varchar(100);not null"`
 	Email     string    `json:"email" gorm:"type:varchar(255)"`
+	EmailHash string    `json:"email_hash" gorm:"type:varchar(32);index"`
 	Website   string    `json:"website" gorm:"type:varchar(500)"`
 	IP        string    `json:"ip" gorm:"type:varchar(50)"`
-	Status    string    `json:"status" gorm:"type:varchar(20);default:pending"` // pending, approved, rejected
+	Status    string    `json:"status" gorm:"type:varchar(20);default:pending"` // pending, approved, rejected, spam
+	// 反垃圾检查的总分与命中原因，供审核参考
+	SpamScore   float64        `json:"spam_score" gorm:"default:0"`
+	SpamReasons datatypes.JSON `json:"spam_reasons" gorm:"type:json"`
+	// 匿名评论者修改/撤回评论用的令牌，只保存 SHA-256 哈希
+	EditTokenHash string     `json:"-" gorm:"type:varchar(64)"`
+	EditedAt      *time.Time `json:"edited_at"`
+	// 从第三方评论系统导入时的来源标识，如 disqus:123，用于重复导入时跳过
+	ImportRef string `json:"import_ref,omitempty" gorm:"type:varchar(100);index"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	
-	ArticleID uint    `json:"article_id"`
-	Article   Article `json:"article" gorm:"foreignKey:ArticleID"`
+	// 评论所属对象，如 article/1、lab/3、guestbook/0
+	TargetType string `json:"target_type" gorm:"type:varchar(20);not null;default:article;index:idx_comments_target"`
+	TargetID   uint   `json:"target_id" gorm:"not null;default:0;index:idx_comments_target"`
 	ParentID  *uint   `json:"parent_id"`
 	Parent    *Comment `json:"parent" gorm:"foreignKey:ParentID"`
 	Replies   []Comment `json:"replies" gorm:"foreignKey:ParentID"`
 }
+
+// BeforeSave 保存时同步邮箱哈希，用于识别可信评论者和生成头像
+func (c *Comment) BeforeSave(tx *gorm.DB) error {
+	if c.Email != "" {
+		c.EmailHash = utils.EmailHash(c.Email)
+	} else {
+		c.EmailHash = ""
+	}
+	return nil
+}
+
+// CommentRevision 评论被作者修改前的版本，供审核时查看修改历史
+type CommentRevision struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CommentID   uint      `json:"comment_id" gorm:"index;not null"`
+	Content     string    `json:"content" gorm:"type:text;not null"`
+	ContentHTML string    `json:"content_html" gorm:"type:text"`
+	Status      string    `json:"status" gorm:"type:varchar(20)"` // 修改前的状态
+	IP          string    `json:"ip" gorm:"type:varchar(50)"`     // 该版本提交时的 IP
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CommentFormNonce 已用于发表评论的表单令牌 nonce，令牌过期后由下一次写入顺带清理
+type CommentFormNonce struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Nonce     string    `json:"-" gorm:"type:varchar(32);uniqueIndex;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -22,6 +22,8 @@ type Lab struct {
 	ResourceLinks datatypes.JSON `json:"resource_links" gorm:"type:json"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
+	SEO           SEO            `json:"seo" gorm:"embedded;embeddedPrefix:seo_"`
+	SEOEffective  *SEO           `json:"seo_effective,omitempty" gorm:"-"` // 填充默认值后的 SEO，仅用于输出，不落库
 }
 
 // LabResource 外链资源信息
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailUnsubscribe 退订邮件通知的邮箱，只保存邮箱哈希
+type EmailUnsubscribe struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	EmailHash string    `json:"email_hash" gorm:"type:varchar(64);uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthState 跳转到第三方登录前保存的 state、nonce 和 PKCE code_verifier，回调时使用一次后删除
+type OAuthState struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	StateHash    string    `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Provider     string    `json:"provider" gorm:"type:varchar(50);not null"`
+	Nonce        string    `json:"-" gorm:"type:varchar(64);not null"`
+	CodeVerifier string    `json:"-" gorm:"type:varchar(128);not null"`
+	LinkUserID   *uint     `json:"link_user_id"` // 已登录用户关联新身份时设置，为空表示登录
+	ExpiresAt    time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
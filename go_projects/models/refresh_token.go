@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken 刷新令牌，只保存哈希。每次刷新都会轮换，同一次登录产生的令牌属于同一个 FamilyID
+type RefreshToken struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	FamilyID     string     `json:"family_id" gorm:"type:varchar(64);not null;index"`
+	TokenHash    string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"` // 轮换后的新令牌；已轮换的令牌再次出现即视为被盗用
+	IP           string     `json:"ip" gorm:"type:varchar(50)"`
+	UserAgent    string     `json:"user_agent" gorm:"type:varchar(255)"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
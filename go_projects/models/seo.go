@@ -0,0 +1,11 @@
+package models
+
+// SEO 页面元信息，以 seo_ 前缀的列嵌入到文章、分类、标签和专题表中
+type SEO struct {
+	MetaTitle       string `json:"meta_title" gorm:"type:varchar(255)"`
+	MetaDescription string `json:"meta_description" gorm:"type:varchar(500)"`
+	Keywords        string `json:"keywords" gorm:"type:varchar(255)"`
+	CanonicalURL    string `json:"canonical_url" gorm:"type:varchar(500)"`
+	OGImage         string `json:"og_image" gorm:"type:varchar(500)"`
+	NoIndex         bool   `json:"noindex" gorm:"default:false"`
+}
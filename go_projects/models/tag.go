@@ -6,11 +6,30 @@ import (
 
 // Tag 标签模型
 type Tag struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Name         string    `json:"name" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Slug         string    `json:"slug" gorm:"type:varchar(100);uniqueIndex;not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	SEO          SEO       `json:"seo" gorm:"embedded;embeddedPrefix:seo_"`
+	SEOEffective *SEO      `json:"seo_effective,omitempty" gorm:"-"` // 填充默认值后的 SEO，仅用于输出，不落库
+
+	Articles []Article  `json:"articles" gorm:"many2many:article_tags;"`
+	Aliases  []TagAlias `json:"aliases,omitempty" gorm:"foreignKey:TagID"`
+}
+
+// TagAlias 标签别名，旧 slug 或合并进来的标签会指向规范标签
+type TagAlias struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"type:varchar(100);uniqueIndex;not null"`
+	TagID     uint      `json:"tag_id" gorm:"index;not null"`
+	Name      string    `json:"name" gorm:"type:varchar(100)"`
 	Slug      string    `json:"slug" gorm:"type:varchar(100);uniqueIndex;not null"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	
-	Articles []Article `json:"articles" gorm:"many2many:article_tags;"`
+}
+
+// TagWithStats 带使用统计的标签
+type TagWithStats struct {
+	Tag
+	ArticleCount int64      `json:"article_count"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
 }
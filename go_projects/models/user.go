@@ -4,15 +4,39 @@ import (
 	"time"
 )
 
+// 内置角色，权限见 services/rbac.go
+const (
+	RoleAdmin     = "admin"
+	RoleEditor    = "editor"
+	RoleAuthor    = "author"
+	RoleModerator = "moderator"
+	RoleReader    = "reader"
+)
+
 // User 用户模型
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Username  string    `json:"username" gorm:"type:varchar(100);uniqueIndex;not null"`
-	Email     string    `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
-	Password  string    `json:"-" gorm:"type:varchar(255);not null"`
-	Avatar    string    `json:"avatar" gorm:"type:varchar(500)"`
-	Role      string    `json:"role" gorm:"type:varchar(20);default:user"` // admin, user
-	Bio       string    `json:"bio" gorm:"type:text"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                    uint       `json:"id" gorm:"primaryKey"`
+	Username              string     `json:"username" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Email                 string     `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Password              string     `json:"-" gorm:"type:varchar(255);not null"`
+	Avatar                string     `json:"avatar" gorm:"type:varchar(500)"`
+	Role                  string     `json:"role" gorm:"type:varchar(20);default:reader"` // admin, editor, author, moderator, reader
+	Bio                   string     `json:"bio" gorm:"type:text"`
+	TokenVersion          int        `json:"-" gorm:"not null;default:0"` // 递增后已签发的访问令牌全部失效
+	EmailVerifiedAt       *time.Time `json:"email_verified_at"`
+	TOTPSecret            string     `json:"-" gorm:"column:totp_secret;type:varchar(64)"`      // 两步验证密钥（Base32），未确认前 TOTPEnabledAt 为空
+	TOTPEnabledAt         *time.Time `json:"totp_enabled_at" gorm:"column:totp_enabled_at"`     // 两步验证启用时间
+	TOTPLastStep          int64      `json:"-" gorm:"column:totp_last_step;not null;default:0"` // 最近一次使用的时间步，防止验证码重放
+	DisabledAt            *time.Time `json:"-"`                                                 // 禁用时间，禁用期间无法登录，已签发的令牌也会被拒绝
+	DisabledUntil         *time.Time `json:"-"`                                                 // 临时封禁的截止时间，为空表示一直禁用到手动启用
+	DisabledReason        string     `json:"-" gorm:"type:varchar(255)"`
+	PasswordUnset         bool       `json:"-" gorm:"not null;default:false"` // 第三方注册时生成的随机密码，用户还没有设置过自己的密码
+	PasswordResetRequired bool       `json:"-" gorm:"not null;default:false"` // 管理员强制重置了密码，通过重置链接设置新密码前不能登录
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// IsDisabled 账号当前是否处于禁用状态，临时封禁到期后自动恢复
+func (u *User) IsDisabled(now time.Time) bool {
+	return u.DisabledAt != nil && (u.DisabledUntil == nil || now.Before(*u.DisabledUntil))
 }
@@ -12,8 +12,12 @@ type ArticleRepository interface {
 	FindBySlug(slug string) (*models.Article, error)
 	Create(article *models.Article) error
 	Update(article *models.Article) error
+	CreateWithSlug(article *models.Article, slugFn SlugFunc) error
+	UpdateWithSlug(article *models.Article, slugFn SlugFunc) error
 	Delete(article *models.Article) error
 	CountBySlug(slug string) (int64, error)
+	FindAllWithContent() ([]models.Article, error)
+	FindSlugs() ([]models.Article, error)
 }
 
 type articleRepository struct {
@@ -34,7 +38,13 @@ func (r *articleRepository) FindAll(page, pageSize int, filters map[string]inter
 		query = query.Where("status = ?", status)
 	}
 
-	if categoryID, ok := filters["category_id"]; ok && categoryID != "" {
+	if authorID, ok := filters["author_id"]; ok {
+		query = query.Where("author_id = ?", authorID)
+	}
+
+	if categoryIDs, ok := filters["category_ids"]; ok {
+		query = query.Where("category_id IN ?", categoryIDs)
+	} else if categoryID, ok := filters["category_id"]; ok && categoryID != "" {
 		query = query.Where("category_id = ?", categoryID)
 	}
 
@@ -58,14 +68,14 @@ func (r *articleRepository) FindAll(page, pageSize int, filters map[string]inter
 func (r *articleRepository) FindByID(id string) (*models.Article, error) {
 	var article models.Article
 	err := r.db.Preload("Author").Preload("Category").Preload("Tags").
-		Preload("Comments", "status = ?", "approved").Preload("Comments.Replies").
 		First(&article, id).Error
 	return &article, err
 }
 
 func (r *articleRepository) FindBySlug(slug string) (*models.Article, error) {
 	var article models.Article
-	err := r.db.Where("slug = ?", slug).First(&article).Error
+	err := r.db.Preload("Author").Preload("Category").Preload("Tags").
+		Where("slug = ?", slug).First(&article).Error
 	return &article, err
 }
 
@@ -77,8 +87,14 @@ func (r *articleRepository) Update(article *models.Article) error {
 	return r.db.Save(article).Error
 }
 
+// Delete 删除文章及其下的评论
 func (r *articleRepository) Delete(article *models.Article) error {
-	return r.db.Delete(article).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTargetComments(tx, models.CommentTargetArticle, article.ID); err != nil {
+			return err
+		}
+		return tx.Delete(article).Error
+	})
 }
 
 func (r *articleRepository) CountBySlug(slug string) (int64, error) {
@@ -86,3 +102,29 @@ func (r *articleRepository) CountBySlug(slug string) (int64, error) {
 	err := r.db.Model(&models.Article{}).Where("slug = ?", slug).Count(&count).Error
 	return count, err
 }
+
+func (r *articleRepository) FindAllWithContent() ([]models.Article, error) {
+	var articles []models.Article
+	err := r.db.Select("id", "title", "slug", "content", "cover_image", "status").
+		Order("id ASC").Find(&articles).Error
+	return articles, err
+}
+
+func (r *articleRepository) CreateWithSlug(article *models.Article, slugFn SlugFunc) error {
+	return saveWithSlug(r.db, "articles", slugFn, func(slug string) { article.Slug = slug }, func() uint { return article.ID }, func(tx *gorm.DB) error {
+		return tx.Create(article).Error
+	})
+}
+
+func (r *articleRepository) UpdateWithSlug(article *models.Article, slugFn SlugFunc) error {
+	return saveWithSlug(r.db, "articles", slugFn, func(slug string) { article.Slug = slug }, func() uint { return article.ID }, func(tx *gorm.DB) error {
+		return tx.Save(article).Error
+	})
+}
+
+// FindSlugs 只查询所有文章的 ID、slug 和标题
+func (r *articleRepository) FindSlugs() ([]models.Article, error) {
+	var articles []models.Article
+	err := r.db.Select("id", "slug", "title").Find(&articles).Error
+	return articles, err
+}
@@ -3,15 +3,30 @@ package repositories
 import (
 	"blog-system/database"
 	"blog-system/models"
+	"errors"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrCategoryNotEmpty 删除时分类下仍有文章且未指定迁移目标
+var ErrCategoryNotEmpty = errors.New("category still has articles")
+
 type CategoryRepository interface {
 	FindAll() ([]models.Category, error)
 	FindByID(id uint) (*models.Category, error)
+	// FindWithAncestors 查询指定分类及其全部上级分类，用于生成面包屑
+	FindWithAncestors(ids []uint) ([]models.Category, error)
 	Create(category *models.Category) error
 	Update(category *models.Category) error
-	Delete(category *models.Category) error
+	CreateWithSlug(category *models.Category, slugFn SlugFunc) error
+	UpdateWithSlug(category *models.Category, slugFn SlugFunc) error
+	// Delete 删除分类并把子分类挂到上级；reassignTo 为空且分类下仍有文章时返回 ErrCategoryNotEmpty，
+	// 迁移目标不存在时返回 gorm.ErrRecordNotFound。检查和删除在同一事务内，并锁定相关分类
+	Delete(category *models.Category, reassignTo *uint) (int64, error)
+	CountArticles(id uint) (int64, error)
+	CountPublishedArticles() (map[uint]int64, error)
+	FindAllWithStats() ([]models.CategoryWithStats, error)
 }
 
 type categoryRepository struct {
@@ -34,6 +49,30 @@ func (r *categoryRepository) FindByID(id uint) (*models.Category, error) {
 	return &category, err
 }
 
+func (r *categoryRepository) FindWithAncestors(ids []uint) ([]models.Category, error) {
+	var categories []models.Category
+	seen := make(map[uint]bool)
+	for pending := ids; len(pending) > 0; {
+		var batch []models.Category
+		if err := r.db.Where("id IN ?", pending).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, category := range batch {
+			seen[category.ID] = true
+		}
+		// 逐层向上查找尚未加载的父分类
+		pending = pending[:0:0]
+		for _, category := range batch {
+			if category.ParentID != nil && !seen[*category.ParentID] {
+				seen[*category.ParentID] = true
+				pending = append(pending, *category.ParentID)
+			}
+		}
+		categories = append(categories, batch...)
+	}
+	return categories, nil
+}
+
 func (r *categoryRepository) Create(category *models.Category) error {
 	return r.db.Create(category).Error
 }
@@ -42,6 +81,110 @@ func (r *categoryRepository) Update(category *models.Category) error {
 	return r.db.Save(category).Error
 }
 
-func (r *categoryRepository) Delete(category *models.Category) error {
-	return r.db.Delete(category).Error
+// Delete 在同一事务内把文章迁移到 reassignTo、子分类上移到父级，再删除分类，返回迁移的文章数
+func (r *categoryRepository) Delete(category *models.Category, reassignTo *uint) (int64, error) {
+	var moved int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定分类行，文章外键检查需要读取该行，并发创建文章会等待删除完成
+		ids := []uint{category.ID}
+		if reassignTo != nil {
+			ids = append(ids, *reassignTo)
+		}
+		var locked []models.Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Find(&locked).Error; err != nil {
+			return err
+		}
+		if len(locked) != len(ids) {
+			return gorm.ErrRecordNotFound
+		}
+
+		if reassignTo != nil {
+			result := tx.Model(&models.Article{}).Where("category_id = ?", category.ID).Update("category_id", *reassignTo)
+			if result.Error != nil {
+				return result.Error
+			}
+			moved = result.RowsAffected
+		} else {
+			var count int64
+			if err := tx.Model(&models.Article{}).Where("category_id = ?", category.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrCategoryNotEmpty
+			}
+		}
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID).Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+	return moved, err
+}
+
+func (r *categoryRepository) CountArticles(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Article{}).Where("category_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *categoryRepository) CountPublishedArticles() (map[uint]int64, error) {
+	var rows []struct {
+		CategoryID uint
+		Count      int64
+	}
+	err := r.db.Model(&models.Article{}).
+		Select("category_id, COUNT(*) AS count").
+		Where("status = ?", "published").
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *categoryRepository) FindAllWithStats() ([]models.CategoryWithStats, error) {
+	categories, err := r.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []usageRow
+	err = r.db.Model(&models.Article{}).
+		Select("category_id AS id, COUNT(*) AS article_count, MAX(published_at) AS last_used_at").
+		Where("status = ?", "published").
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	usage := indexUsageRows(rows)
+
+	results := make([]models.CategoryWithStats, 0, len(categories))
+	for _, category := range categories {
+		stats := models.CategoryWithStats{Category: category}
+		if row, ok := usage[category.ID]; ok {
+			stats.ArticleCount = row.ArticleCount
+			stats.LastUsedAt = parseDBTime(row.LastUsedAt)
+		}
+		results = append(results, stats)
+	}
+	return results, nil
+}
+
+func (r *categoryRepository) CreateWithSlug(category *models.Category, slugFn SlugFunc) error {
+	return saveWithSlug(r.db, "categories", slugFn, func(slug string) { category.Slug = slug }, func() uint { return category.ID }, func(tx *gorm.DB) error {
+		return tx.Create(category).Error
+	})
+}
+
+func (r *categoryRepository) UpdateWithSlug(category *models.Category, slugFn SlugFunc) error {
+	return saveWithSlug(r.db, "categories", slugFn, func(slug string) { category.Slug = slug }, func() uint { return category.ID }, func(tx *gorm.DB) error {
+		return tx.Save(category).Error
+	})
 }
@@ -3,16 +3,35 @@ package repositories
 import (
 	"blog-system/database"
 	"blog-system/models"
+	"errors"
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// ErrFormNonceUsed 表单令牌的 nonce 已被其他评论使用
+var ErrFormNonceUsed = errors.New("form nonce already used")
+
 type CommentRepository interface {
 	FindAll(page, pageSize int) ([]models.Comment, int64, error)
 	FindPending(page, pageSize int) ([]models.Comment, int64, error)
+	FindApproved(page, pageSize int) ([]models.Comment, int64, error)
 	FindByID(id uint) (*models.Comment, error)
+	FindApprovedByTarget(targetType string, targetID uint) ([]models.Comment, error)
+	FindByIDs(ids []uint) ([]models.Comment, error)
+	CountDuplicates(content string, since time.Time) (int64, error)
+	CountApprovedByEmailHash(emailHash string) (int64, error)
+	FindByImportRefs(refs []string) ([]models.Comment, error)
 	Create(comment *models.Comment) error
+	// CreateWithFormNonce 在同一事务中保存评论并记录表单令牌的 nonce，nonce 已使用时返回 ErrFormNonceUsed；
+	// nonce 为 nil 时等同于 Create
+	CreateWithFormNonce(comment *models.Comment, nonce *models.CommentFormNonce) error
 	Update(comment *models.Comment) error
+	UpdateWithRevision(comment *models.Comment, revision *models.CommentRevision) error
+	FindRevisions(commentID uint) ([]models.CommentRevision, error)
+	UpdateStatusByIDs(ids []uint, status string) (int64, error)
 	Delete(comment *models.Comment) error
+	DeleteByIDs(ids []uint) (int64, error)
 }
 
 type commentRepository struct {
@@ -27,7 +46,7 @@ func (r *commentRepository) FindAll(page, pageSize int) ([]models.Comment, int64
 	var comments []models.Comment
 	var total int64
 	
-	query := r.db.Preload("Article")
+	query := r.db
 	query.Model(&models.Comment{}).Count(&total)
 
 	offset := (page - 1) * pageSize
@@ -39,7 +58,19 @@ func (r *commentRepository) FindPending(page, pageSize int) ([]models.Comment, i
 	var comments []models.Comment
 	var total int64
 	
-	query := r.db.Preload("Article").Where("status = ?", "pending")
+	query := r.db.Where("status = ?", models.CommentStatusPending)
+	query.Model(&models.Comment{}).Count(&total)
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&comments).Error
+	return comments, total, err
+}
+
+func (r *commentRepository) FindApproved(page, pageSize int) ([]models.Comment, int64, error) {
+	var comments []models.Comment
+	var total int64
+
+	query := r.db.Where("status = ?", models.CommentStatusApproved)
 	query.Model(&models.Comment{}).Count(&total)
 
 	offset := (page - 1) * pageSize
@@ -53,14 +84,157 @@ func (r *commentRepository) FindByID(id uint) (*models.Comment, error) {
 	return &comment, err
 }
 
+// FindApprovedByTarget 一次查出评论对象下所有已审核评论（不分层级），由调用方组装成树
+func (r *commentRepository) FindApprovedByTarget(targetType string, targetID uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, models.CommentStatusApproved).
+		Order("created_at ASC, id ASC").Find(&comments).Error
+	return comments, err
+}
+
+// CountDuplicates 统计指定时间之后内容完全相同的评论数
+func (r *commentRepository) CountDuplicates(content string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Comment{}).
+		Where("content = ? AND created_at >= ?", content, since).
+		Count(&count).Error
+	return count, err
+}
+
 func (r *commentRepository) Create(comment *models.Comment) error {
 	return r.db.Create(comment).Error
 }
 
+func (r *commentRepository) CreateWithFormNonce(comment *models.Comment, nonce *models.CommentFormNonce) error {
+	if nonce == nil {
+		return r.Create(comment)
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 顺带清理过期的 nonce，过期令牌本身已无法通过校验
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.CommentFormNonce{}).Error; err != nil {
+			return err
+		}
+		// 并发提交同一令牌时只有插入成功的一方可以保存评论
+		if err := tx.Create(nonce).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrFormNonceUsed
+			}
+			return err
+		}
+		return tx.Create(comment).Error
+	})
+}
+
 func (r *commentRepository) Update(comment *models.Comment) error {
 	return r.db.Save(comment).Error
 }
 
+// UpdateWithRevision 在同一事务中保存修改前的版本和修改后的评论
+func (r *commentRepository) UpdateWithRevision(comment *models.Comment, revision *models.CommentRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		return tx.Save(comment).Error
+	})
+}
+
+func (r *commentRepository) FindRevisions(commentID uint) ([]models.CommentRevision, error) {
+	var revisions []models.CommentRevision
+	err := r.db.Where("comment_id = ?", commentID).Order("created_at DESC, id DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// Delete 删除评论及其全部回复，避免留下父评论已不存在的孤立回复
 func (r *commentRepository) Delete(comment *models.Comment) error {
-	return r.db.Delete(comment).Error
+	_, err := r.DeleteByIDs([]uint{comment.ID})
+	return err
+}
+
+func (r *commentRepository) FindByIDs(ids []uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Where("id IN ?", ids).Find(&comments).Error
+	return comments, err
+}
+
+func (r *commentRepository) CountApprovedByEmailHash(emailHash string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Comment{}).
+		Where("email_hash = ? AND status = ?", emailHash, models.CommentStatusApproved).
+		Count(&count).Error
+	return count, err
+}
+
+// UpdateStatusByIDs 批量修改状态，返回实际更新的行数
+func (r *commentRepository) UpdateStatusByIDs(ids []uint, status string) (int64, error) {
+	result := r.db.Model(&models.Comment{}).Where("id IN ?", ids).UpdateColumn("status", status)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteByIDs 在同一事务中删除评论及其全部回复，返回实际删除的评论数（含回复）
+func (r *commentRepository) DeleteByIDs(ids []uint) (int64, error) {
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		subtree, err := commentSubtreeIDs(tx, ids)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id IN ?", subtree).Delete(&models.CommentRevision{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id IN ?", subtree).Delete(&models.Comment{})
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
+
+// deleteTargetComments 删除某个评论对象下的全部评论及修订记录，由文章、音乐等仓库在删除对象的事务中调用
+func deleteTargetComments(tx *gorm.DB, targetType string, targetID uint) error {
+	ids := tx.Model(&models.Comment{}).Select("id").Where("target_type = ? AND target_id = ?", targetType, targetID)
+	if err := tx.Where("comment_id IN (?)", ids).Delete(&models.CommentRevision{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("target_type = ? AND target_id = ?", targetType, targetID).Delete(&models.Comment{}).Error
+}
+
+// commentSubtreeIDs 逐层查找回复，返回 ids 及其所有后代评论的 ID
+func commentSubtreeIDs(tx *gorm.DB, ids []uint) ([]uint, error) {
+	all := append([]uint(nil), ids...)
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for level := ids; len(level) > 0; {
+		var children []uint
+		if err := tx.Model(&models.Comment{}).Where("parent_id IN ?", level).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+		level = level[:0:0]
+		for _, id := range children {
+			if !seen[id] {
+				seen[id] = true
+				level = append(level, id)
+				all = append(all, id)
+			}
+		}
+	}
+	return all, nil
+}
+
+// FindByImportRefs 查询已导入过的评论（只包含 ID、评论对象与导入标识），按批查询避免 IN 参数过多
+func (r *commentRepository) FindByImportRefs(refs []string) ([]models.Comment, error) {
+	var comments []models.Comment
+	for start := 0; start < len(refs); start += 500 {
+		end := start + 500
+		if end > len(refs) {
+			end = len(refs)
+		}
+		var batch []models.Comment
+		if err := r.db.Select("id", "target_type", "target_id", "import_ref").Where("import_ref IN ?", refs[start:end]).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		comments = append(comments, batch...)
+	}
+	return comments, nil
 }
@@ -12,6 +12,8 @@ type LabRepository interface {
 	FindBySlug(slug string) (*models.Lab, error)
 	Create(lab *models.Lab) error
 	Update(lab *models.Lab) error
+	CreateWithSlug(lab *models.Lab, slugFn SlugFunc) error
+	UpdateWithSlug(lab *models.Lab, slugFn SlugFunc) error
 	Delete(lab *models.Lab) error
 }
 
@@ -49,6 +51,24 @@ func (r *labRepository) Update(lab *models.Lab) error {
 	return r.db.Save(lab).Error
 }
 
+// Delete 删除实验室模块及其下的评论
 func (r *labRepository) Delete(lab *models.Lab) error {
-	return r.db.Delete(lab).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTargetComments(tx, models.CommentTargetLab, lab.ID); err != nil {
+			return err
+		}
+		return tx.Delete(lab).Error
+	})
+}
+
+func (r *labRepository) CreateWithSlug(lab *models.Lab, slugFn SlugFunc) error {
+	return saveWithSlug(r.db, "labs", slugFn, func(slug string) { lab.Slug = slug }, func() uint { return lab.ID }, func(tx *gorm.DB) error {
+		return tx.Create(lab).Error
+	})
+}
+
+func (r *labRepository) UpdateWithSlug(lab *models.Lab, slugFn SlugFunc) error {
+	return saveWithSlug(r.db, "labs", slugFn, func(slug string) { lab.Slug = slug }, func() uint { return lab.ID }, func(tx *gorm.DB) error {
+		return tx.Save(lab).Error
+	})
 }
@@ -45,8 +45,14 @@ func (r *musicRepository) Update(music *models.Music) error {
 	return r.db.Save(music).Error
 }
 
+// Delete 删除音乐及其下的评论
 func (r *musicRepository) Delete(music *models.Music) error {
-	return r.db.Delete(music).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTargetComments(tx, models.CommentTargetMusic, music.ID); err != nil {
+			return err
+		}
+		return tx.Delete(music).Error
+	})
 }
 
 func (r *musicRepository) FindPlaylists() ([]models.Playlist, error) {
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"blog-system/database"
+	"blog-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	Create(reset *models.PasswordReset) error
+	FindByHash(hash string) (*models.PasswordReset, error)
+	// Consume 标记令牌已使用、更新密码、确认邮箱并吊销该用户全部会话；令牌已被使用时返回 gorm.ErrRecordNotFound
+	Consume(reset *models.PasswordReset, passwordHash string) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository() PasswordResetRepository {
+	return &passwordResetRepository{db: database.DB}
+}
+
+func (r *passwordResetRepository) Create(reset *models.PasswordReset) error {
+	return r.db.Create(reset).Error
+}
+
+func (r *passwordResetRepository) FindByHash(hash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	err := r.db.Where("token_hash = ?", hash).First(&reset).Error
+	return &reset, err
+}
+
+func (r *passwordResetRepository) Consume(reset *models.PasswordReset, passwordHash string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// 同一用户尚未使用的其他重置链接一并作废
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		// 重置链接只发往账号邮箱，能使用即证明拥有该邮箱，未验证的邮箱一并标记为已验证
+		if err := tx.Model(&models.User{}).Where("id = ?", reset.UserID).Updates(map[string]interface{}{
+			"password":                passwordHash,
+			"token_version":           gorm.Expr("token_version + ?", 1),
+			"email_verified_at":       gorm.Expr("COALESCE(email_verified_at, ?)", now),
+			"password_unset":          false,
+			"password_reset_required": false,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", reset.UserID).
+			Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SlugLookup 在当前事务内查询某张表已占用的 slug
+type SlugLookup interface {
+	// SlugsWithPrefix 返回等于 base 或以 "base-" 开头的已有 slug，并对命中的行加锁
+	SlugsWithPrefix(base string, excludeID uint) ([]string, error)
+	// RecordID 正在保存的记录的主键；新记录插入之前为 0
+	RecordID() uint
+}
+
+// SlugFunc 在事务内生成最终 slug；返回空字符串表示以记录的主键作为 slug（数字编号），
+// 此时 saveWithSlug 会先插入记录，再用取得的主键重新调用
+type SlugFunc func(lookup SlugLookup) (string, error)
+
+type slugLookup struct {
+	tx       *gorm.DB
+	table    string
+	recordID func() uint
+}
+
+func (l *slugLookup) SlugsWithPrefix(base string, excludeID uint) ([]string, error) {
+	var slugs []string
+	query := l.tx.Table(l.table).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("slug = ? OR slug LIKE ?", base, base+"-%")
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	err := query.Pluck("slug", &slugs).Error
+	return slugs, err
+}
+
+func (l *slugLookup) RecordID() uint {
+	return l.recordID()
+}
+
+// saveWithSlug 在同一事务内生成 slug 并保存记录；recordID 返回记录当前的主键
+func saveWithSlug(db *gorm.DB, table string, slugFn SlugFunc, setSlug func(string), recordID func() uint, save func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		lookup := &slugLookup{tx: tx, table: table, recordID: recordID}
+		slug, err := slugFn(lookup)
+		if err != nil {
+			return err
+		}
+		if slug != "" {
+			setSlug(slug)
+			return save(tx)
+		}
+
+		// 数字编号：先以临时 slug 插入取得真实主键，再在同一事务内改为最终 slug
+		setSlug("pending-" + strconv.FormatInt(time.Now().UnixNano(), 36))
+		if err := save(tx); err != nil {
+			return err
+		}
+		if slug, err = slugFn(lookup); err != nil {
+			return err
+		}
+		if slug == "" {
+			return errors.New("slug function returned an empty slug for a saved record")
+		}
+		setSlug(slug)
+		return tx.Table(table).Where("id = ?", recordID()).Update("slug", slug).Error
+	})
+}
@@ -3,16 +3,38 @@ package repositories
 import (
 	"blog-system/database"
 	"blog-system/models"
+	"errors"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrTagNotFound 合并时来源标签不存在（可能已被删除或合并）
+var ErrTagNotFound = errors.New("tag not found")
+
 type TagRepository interface {
 	FindAll() ([]models.Tag, error)
 	FindByID(id uint) (*models.Tag, error)
 	FindByIds(ids []uint) ([]models.Tag, error)
+	FindBySlug(slug string) (*models.Tag, error)
+	FindAllWithStats() ([]models.TagWithStats, error)
 	Create(tag *models.Tag) error
+	// CreateWithSlug 在同一事务内生成 slug 并创建标签
+	CreateWithSlug(tag *models.Tag, slugFn SlugFunc) error
 	Update(tag *models.Tag) error
-	Delete(tag *models.Tag) error
+	Delete(tag *models.Tag) (int64, error)
+
+	FindAliases(tagID uint) ([]models.TagAlias, error)
+	FindAllAliases() ([]models.TagAlias, error)
+	FindAliasBySlug(slug string) (*models.TagAlias, error)
+	FindAliasByID(id uint) (*models.TagAlias, error)
+	CreateAlias(alias *models.TagAlias) error
+	DeleteAlias(alias *models.TagAlias) error
+	// Rename 保存改名后的标签；slugFn 非空时在事务内生成新 slug，旧 slug 和 oldName 记为别名
+	Rename(tag *models.Tag, oldName string, slugFn SlugFunc) error
+	// Merge 来源标签中有不存在的 ID 时返回 ErrTagNotFound，不做任何修改
+	Merge(sourceIDs []uint, targetID uint) error
 }
 
 type tagRepository struct {
@@ -41,14 +63,235 @@ func (r *tagRepository) FindByIds(ids []uint) ([]models.Tag, error) {
 	return tags, err
 }
 
+func (r *tagRepository) FindBySlug(slug string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Where("slug = ?", slug).First(&tag).Error
+	return &tag, err
+}
+
+// FindAllWithStats 通过一次聚合查询统计每个标签的已发布文章数和最近使用时间
+func (r *tagRepository) FindAllWithStats() ([]models.TagWithStats, error) {
+	tags, err := r.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []usageRow
+	err = r.db.Table("article_tags").
+		Select("article_tags.tag_id AS id, COUNT(articles.id) AS article_count, MAX(articles.published_at) AS last_used_at").
+		Joins("JOIN articles ON articles.id = article_tags.article_id").
+		Where("articles.status = ?", "published").
+		Group("article_tags.tag_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	usage := indexUsageRows(rows)
+
+	results := make([]models.TagWithStats, 0, len(tags))
+	for _, tag := range tags {
+		stats := models.TagWithStats{Tag: tag}
+		if row, ok := usage[tag.ID]; ok {
+			stats.ArticleCount = row.ArticleCount
+			stats.LastUsedAt = parseDBTime(row.LastUsedAt)
+		}
+		results = append(results, stats)
+	}
+	return results, nil
+}
+
 func (r *tagRepository) Create(tag *models.Tag) error {
 	return r.db.Create(tag).Error
 }
 
+func (r *tagRepository) CreateWithSlug(tag *models.Tag, slugFn SlugFunc) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		slug, err := slugFn(&tagSlugLookup{tx: tx, tagID: tag.ID})
+		if err != nil {
+			return err
+		}
+		tag.Slug = slug
+		return tx.Create(tag).Error
+	})
+}
+
 func (r *tagRepository) Update(tag *models.Tag) error {
 	return r.db.Save(tag).Error
 }
 
-func (r *tagRepository) Delete(tag *models.Tag) error {
-	return r.db.Delete(tag).Error
+// Delete 删除标签及其别名和 article_tags 关联，返回受影响的文章数
+func (r *tagRepository) Delete(tag *models.Tag) (int64, error) {
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("DELETE FROM article_tags WHERE tag_id = ?", tag.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&models.TagAlias{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+	return affected, err
+}
+
+func (r *tagRepository) FindAliases(tagID uint) ([]models.TagAlias, error) {
+	var aliases []models.TagAlias
+	err := r.db.Where("tag_id = ?", tagID).Order("created_at ASC").Find(&aliases).Error
+	return aliases, err
+}
+
+func (r *tagRepository) FindAllAliases() ([]models.TagAlias, error) {
+	var aliases []models.TagAlias
+	err := r.db.Find(&aliases).Error
+	return aliases, err
+}
+
+func (r *tagRepository) FindAliasBySlug(slug string) (*models.TagAlias, error) {
+	var alias models.TagAlias
+	err := r.db.Where("slug = ?", slug).First(&alias).Error
+	return &alias, err
+}
+
+func (r *tagRepository) FindAliasByID(id uint) (*models.TagAlias, error) {
+	var alias models.TagAlias
+	err := r.db.First(&alias, id).Error
+	return &alias, err
+}
+
+func (r *tagRepository) CreateAlias(alias *models.TagAlias) error {
+	return r.db.Create(alias).Error
+}
+
+func (r *tagRepository) DeleteAlias(alias *models.TagAlias) error {
+	return r.db.Delete(alias).Error
+}
+
+// Rename 保存改名后的标签，并在同一事务内把旧 slug 记为别名
+func (r *tagRepository) Rename(tag *models.Tag, oldName string, slugFn SlugFunc) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		oldSlug := tag.Slug
+		if slugFn != nil {
+			slug, err := slugFn(&tagSlugLookup{tx: tx, tagID: tag.ID})
+			if err != nil {
+				return err
+			}
+			tag.Slug = slug
+		}
+
+		// 新 slug 如果曾是别名，则不再作为别名保留
+		if err := tx.Where("slug = ?", tag.Slug).Delete(&models.TagAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(tag).Error; err != nil {
+			return err
+		}
+		if tag.Slug == oldSlug {
+			return nil
+		}
+		oldAlias := models.TagAlias{TagID: tag.ID, Name: oldName, Slug: oldSlug}
+		return tx.Where("slug = ?", oldSlug).
+			Assign(models.TagAlias{TagID: tag.ID, Name: oldName}).
+			FirstOrCreate(&oldAlias).Error
+	})
+}
+
+// tagSlugLookup 标签的 slug 不能与其他标签的别名重复，查询时同时检查两张表
+type tagSlugLookup struct {
+	tx    *gorm.DB
+	tagID uint
+}
+
+func (l *tagSlugLookup) SlugsWithPrefix(base string, excludeID uint) ([]string, error) {
+	slugs, err := (&slugLookup{tx: l.tx, table: "tags"}).SlugsWithPrefix(base, excludeID)
+	if err != nil {
+		return nil, err
+	}
+	var aliases []string
+	query := l.tx.Model(&models.TagAlias{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("slug = ? OR slug LIKE ?", base, base+"-%")
+	if excludeID != 0 {
+		query = query.Where("tag_id <> ?", excludeID)
+	}
+	if err := query.Pluck("slug", &aliases).Error; err != nil {
+		return nil, err
+	}
+	return append(slugs, aliases...), nil
+}
+
+func (l *tagSlugLookup) RecordID() uint {
+	return l.tagID
+}
+
+// Merge 将来源标签的文章关联迁移到目标标签，来源标签的 slug 保留为目标标签的别名
+func (r *tagRepository) Merge(sourceIDs []uint, targetID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var sources []models.Tag
+		if err := tx.Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil {
+			return err
+		}
+		if len(sources) != len(sourceIDs) {
+			return ErrTagNotFound
+		}
+
+		err := tx.Exec(`INSERT INTO article_tags (article_id, tag_id)
+			SELECT DISTINCT article_id, ? FROM article_tags
+			WHERE tag_id IN ? AND article_id NOT IN (
+				SELECT article_id FROM (SELECT article_id FROM article_tags WHERE tag_id = ?) AS existing
+			)`, targetID, sourceIDs, targetID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM article_tags WHERE tag_id IN ?", sourceIDs).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.TagAlias{}).Where("tag_id IN ?", sourceIDs).Update("tag_id", targetID).Error; err != nil {
+			return err
+		}
+		for _, source := range sources {
+			alias := models.TagAlias{TagID: targetID, Name: source.Name, Slug: source.Slug}
+			if err := tx.Where("slug = ?", source.Slug).Assign(alias).FirstOrCreate(&alias).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("id IN ?", sourceIDs).Delete(&models.Tag{}).Error
+	})
+}
+
+// usageRow 聚合统计结果，时间以字符串读取以兼容 MySQL 与 SQLite 的 MAX() 返回类型
+type usageRow struct {
+	ID           uint
+	ArticleCount int64
+	LastUsedAt   *string
+}
+
+func indexUsageRows(rows []usageRow) map[uint]usageRow {
+	usage := make(map[uint]usageRow, len(rows))
+	for _, row := range rows {
+		usage[row.ID] = row
+	}
+	return usage
+}
+
+var dbTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+func parseDBTime(value *string) *time.Time {
+	if value == nil || *value == "" {
+		return nil
+	}
+	for _, layout := range dbTimeLayouts {
+		if t, err := time.ParseInLocation(layout, *value, time.Local); err == nil {
+			return &t
+		}
+	}
+	return nil
 }
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"blog-system/database"
+	"blog-system/models"
+
+	"gorm.io/gorm"
+)
+
+type UnsubscribeRepository interface {
+	IsUnsubscribed(emailHash string) (bool, error)
+	Create(emailHash string) error
+}
+
+type unsubscribeRepository struct {
+	db *gorm.DB
+}
+
+func NewUnsubscribeRepository() UnsubscribeRepository {
+	return &unsubscribeRepository{db: database.DB}
+}
+
+func (r *unsubscribeRepository) IsUnsubscribed(emailHash string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.EmailUnsubscribe{}).Where("email_hash = ?", emailHash).Count(&count).Error
+	return count > 0, err
+}
+
+// Create 重复退订视为成功
+func (r *unsubscribeRepository) Create(emailHash string) error {
+	return r.db.Where(models.EmailUnsubscribe{EmailHash: emailHash}).
+		FirstOrCreate(&models.EmailUnsubscribe{EmailHash: emailHash}).Error
+}
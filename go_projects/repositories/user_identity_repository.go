@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"blog-system/database"
+	"blog-system/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLastIdentity 取消关联后用户将没有任何登录方式
+var ErrLastIdentity = errors.New("cannot remove the last sign-in method")
+
+type UserIdentityRepository interface {
+	FindByProviderSubject(provider, subject string) (*models.UserIdentity, error)
+	ListByUser(userID uint) ([]models.UserIdentity, error)
+	Create(identity *models.UserIdentity) error
+	// CreateWithUser 在同一事务中创建新用户及其第三方身份
+	CreateWithUser(user *models.User, identity *models.UserIdentity) error
+	Update(identity *models.UserIdentity) error
+	// Delete 删除用户自己的身份，不存在时返回 gorm.ErrRecordNotFound；
+	// keepLast 为 true 且这是该用户唯一的身份时返回 ErrLastIdentity
+	Delete(userID, id uint, keepLast bool) error
+	CreateState(state *models.OAuthState) error
+	// ConsumeState 取出并删除未过期的 state，不存在、已使用或已过期时返回 gorm.ErrRecordNotFound
+	ConsumeState(hash string) (*models.OAuthState, error)
+}
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository() UserIdentityRepository {
+	return &userIdentityRepository{db: database.DB}
+}
+
+func (r *userIdentityRepository) FindByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return &identity, err
+}
+
+func (r *userIdentityRepository) ListByUser(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *userIdentityRepository) Create(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *userIdentityRepository) CreateWithUser(user *models.User, identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *userIdentityRepository) Update(identity *models.UserIdentity) error {
+	return r.db.Save(identity).Error
+}
+
+func (r *userIdentityRepository) Delete(userID, id uint, keepLast bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserIdentity{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if !keepLast {
+			return nil
+		}
+		// 删除后再计数，并发取消关联两个身份时后提交的一方会看到 0 并回滚
+		var remaining int64
+		if err := tx.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining == 0 {
+			return ErrLastIdentity
+		}
+		return nil
+	})
+}
+
+func (r *userIdentityRepository) CreateState(state *models.OAuthState) error {
+	// 顺带清理过期未完成的登录
+	if err := r.db.Where("expires_at < ?", time.Now()).Delete(&models.OAuthState{}).Error; err != nil {
+		return err
+	}
+	return r.db.Create(state).Error
+}
+
+func (r *userIdentityRepository) ConsumeState(hash string) (*models.OAuthState, error) {
+	var state models.OAuthState
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state_hash = ?", hash).First(&state).Error; err != nil {
+			return err
+		}
+		// 并发回调时只有删除成功的一方可以继续
+		result := tx.Delete(&models.OAuthState{}, state.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || time.Now().After(state.ExpiresAt) {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	return &state, err
+}
@@ -3,14 +3,45 @@ package repositories
 import (
 	"blog-system/database"
 	"blog-system/models"
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// UserStats 用户及其文章、评论数量，评论按邮箱匹配
+type UserStats struct {
+	models.User  `gorm:"embedded"`
+	ArticleCount int64
+	CommentCount int64
+}
+
 type UserRepository interface {
 	FindByID(id uint) (*models.User, error)
 	FindByUsername(username string) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
+	FindByRole(role string) ([]models.User, error)
+	// CountActiveByRole 该角色未被禁用的用户数
+	CountActiveByRole(role string) (int64, error)
+	// Search 按 search（用户名或邮箱）、role、status（active/disabled）筛选，附带文章和评论数量
+	Search(page, pageSize int, filters map[string]interface{}) ([]UserStats, int64, error)
+	FindStatsByID(id uint) (*UserStats, error)
 	Create(user *models.User) error
 	Update(user *models.User) error
+	// DeleteUnverified 删除邮箱未验证的用户及其会话、重置链接等记录；用户已验证或不存在时返回 false
+	DeleteUnverified(id uint) (bool, error)
+	// UpdatePassword 在同一事务中更新密码、递增 token_version 并吊销全部刷新令牌；
+	// resetRequired 为 true 表示密码由管理员作废，用户需通过重置链接设置新密码后才能登录
+	UpdatePassword(id uint, passwordHash string, resetRequired bool) error
+	// AdvanceTOTPStep 记录已使用的验证码时间步，时间步不大于已记录的值时返回 false（验证码重放）
+	AdvanceTOTPStep(id uint, step int64) (bool, error)
+	// SetTOTPSecret 保存待确认的两步验证密钥，已启用两步验证时返回 false
+	SetTOTPSecret(id uint, secret string) (bool, error)
+	// EnableTOTP 启用两步验证；密钥已被重新生成或已启用时返回 false
+	EnableTOTP(id uint, secret string, at time.Time) (bool, error)
+	// DisableTOTP 清除两步验证密钥和已使用的时间步
+	DisableTOTP(id uint) error
+	// SetDisabled 只更新禁用相关字段，disabledAt 为 nil 表示启用
+	SetDisabled(id uint, disabledAt, until *time.Time, reason string) error
 }
 
 type userRepository struct {
@@ -33,6 +64,74 @@ func (r *userRepository) FindByUsername(username string) (*models.User, error) {
 	return &user, err
 }
 
+func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	return &user, err
+}
+
+func (r *userRepository) FindByRole(role string) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("role = ?", role).Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) CountActiveByRole(role string) (int64, error) {
+	var count int64
+	err := activeUsers(r.db.Model(&models.User{}), time.Now()).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
+func (r *userRepository) Search(page, pageSize int, filters map[string]interface{}) ([]UserStats, int64, error) {
+	var users []UserStats
+	var total int64
+
+	query := r.db.Model(&models.User{})
+	if search, ok := filters["search"]; ok && search != "" {
+		pattern := "%" + search.(string) + "%"
+		query = query.Where("username LIKE ? OR email LIKE ?", pattern, pattern)
+	}
+	if role, ok := filters["role"]; ok && role != "" {
+		query = query.Where("role = ?", role)
+	}
+	switch filters["status"] {
+	case "active":
+		query = activeUsers(query, time.Now())
+	case "disabled":
+		query = query.Where("disabled_at IS NOT NULL AND (disabled_until IS NULL OR disabled_until > ?)", time.Now())
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := withUserCounts(query).Order("users.id ASC").Offset(offset).Limit(pageSize).Scan(&users).Error
+	return users, total, err
+}
+
+func (r *userRepository) FindStatsByID(id uint) (*UserStats, error) {
+	var users []UserStats
+	err := withUserCounts(r.db.Model(&models.User{})).Where("users.id = ?", id).Limit(1).Scan(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &users[0], nil
+}
+
+func activeUsers(query *gorm.DB, now time.Time) *gorm.DB {
+	return query.Where("disabled_at IS NULL OR (disabled_until IS NOT NULL AND disabled_until <= ?)", now)
+}
+
+func withUserCounts(query *gorm.DB) *gorm.DB {
+	return query.Select("users.*, " +
+		"(SELECT COUNT(*) FROM articles WHERE articles.author_id = users.id) AS article_count, " +
+		"(SELECT COUNT(*) FROM comments WHERE LOWER(comments.email) = LOWER(users.email)) AS comment_count")
+}
+
 func (r *userRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
@@ -40,3 +139,74 @@ func (r *userRepository) Create(user *models.User) error {
 func (r *userRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
+
+func (r *userRepository) DeleteUnverified(id uint) (bool, error) {
+	deleted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND email_verified_at IS NULL", id).Delete(&models.User{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+		for _, record := range []interface{}{&models.RefreshToken{}, &models.PasswordReset{}, &models.RecoveryCode{}, &models.UserIdentity{}} {
+			if err := tx.Where("user_id = ?", id).Delete(record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (r *userRepository) UpdatePassword(id uint, passwordHash string, resetRequired bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"password":                passwordHash,
+			"token_version":           gorm.Expr("token_version + ?", 1),
+			"password_unset":          false,
+			"password_reset_required": resetRequired,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+func (r *userRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		UpdateColumn("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) SetTOTPSecret(id uint, secret string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_enabled_at IS NULL", id).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) EnableTOTP(id uint, secret string, at time.Time) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_enabled_at IS NULL AND totp_secret = ?", id, secret).
+		UpdateColumn("totp_enabled_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) DisableTOTP(id uint) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+func (r *userRepository) SetDisabled(id uint, disabledAt, until *time.Time, reason string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"disabled_at":     disabledAt,
+		"disabled_until":  until,
+		"disabled_reason": reason,
+	}).Error
+}
@@ -4,6 +4,7 @@ import (
 	"blog-system/config"
 	"blog-system/controllers"
 	"blog-system/middleware"
+	"blog-system/models"
 	"blog-system/services"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +24,10 @@ func SetupRoutes() *gin.Engine {
 	commentService := services.NewCommentService()
 	musicService := services.NewMusicService()
 	labService := services.NewLabService()
+	linkCheckerService := services.NewLinkCheckerService()
+	notificationService := services.NewNotificationService(services.DefaultMailQueue())
+	commentImportService := services.NewCommentImportService()
+	captchaService := services.NewCaptchaService()
 
 	// 初始化控制器
 	authController := controllers.NewAuthController(userService)
@@ -31,18 +36,52 @@ func SetupRoutes() *gin.Engine {
 	categoryController := controllers.NewCategoryController(categoryService)
 	tagController := controllers.NewTagController(tagService)
 	commentController := controllers.NewCommentController(commentService)
-	linkController := controllers.NewLinkController() // LinkController not refactored yet, assuming it doesn't need service or I missed it.
+	linkController := controllers.NewLinkController()     // LinkController not refactored yet, assuming it doesn't need service or I missed it.
 	uploadController := controllers.NewUploadController() // UploadController not refactored yet.
 	labController := controllers.NewLabController(labService, articleService)
+	linkCheckController := controllers.NewLinkCheckController(linkCheckerService)
+	notificationController := controllers.NewNotificationController(notificationService)
+	commentImportController := controllers.NewCommentImportController(commentImportService)
+	captchaController := controllers.NewCaptchaController(captchaService)
+	userController := controllers.NewUserController(userService, articleService)
+
+	// 匿名写操作需要工作量证明（captcha.enabled 关闭时直接放行）
+	commentCaptcha := middleware.CaptchaMiddleware(captchaService, services.CaptchaScopeComment)
+	// 按 auth.require_verified_email 配置要求先验证邮箱
+	verifiedCommenter := middleware.RequireVerifiedEmail(services.VerifiedEmailForComment)
+	verifiedAuthor := middleware.RequireVerifiedEmail(services.VerifiedEmailForAuthor)
+	// 按角色权限控制管理操作，权限定义见 services/rbac.go
+	canWriteArticles := middleware.RequirePermission(services.PermArticleWrite)
+	canModerate := middleware.RequirePermission(services.PermCommentModerate)
+	canManageTaxonomy := middleware.RequirePermission(services.PermTaxonomyManage)
+	canManageMusic := middleware.RequirePermission(services.PermMusicManage)
+	canManageLinks := middleware.RequirePermission(services.PermLinkManage)
+	canUpload := middleware.RequirePermission(services.PermMediaUpload)
+	canManageMedia := middleware.RequirePermission(services.PermMediaManage)
+	canManageSite := middleware.RequirePermission(services.PermSiteManage)
+	canManageUsers := middleware.RequirePermission(services.PermUserManage)
 
 	// 公开路由
 	api := r.Group("/api")
 	{
+		// 工作量证明验证码
+		api.GET("/captcha/challenge", captchaController.GetChallenge)
+
 		// 认证
 		auth := api.Group("/auth")
 		{
-			auth.POST("/register", authController.Register)
+			auth.POST("/register", middleware.CaptchaMiddleware(captchaService, services.CaptchaScopeRegister), authController.Register)
 			auth.POST("/login", authController.Login)
+			auth.POST("/refresh", authController.Refresh)
+			auth.POST("/logout", authController.Logout)
+			auth.POST("/forgot-password", authController.ForgotPassword)
+			auth.POST("/reset-password", authController.ResetPassword)
+			auth.GET("/verify-email", authController.VerifyEmail)
+			auth.POST("/resend-verification", authController.ResendVerification)
+			auth.POST("/2fa/verify", authController.VerifyTwoFactor)
+			auth.GET("/oauth/providers", authController.OAuthProviders)
+			auth.GET("/oauth/:provider/login", authController.OAuthLogin)
+			auth.GET("/oauth/:provider/callback", authController.OAuthCallback)
 		}
 
 		// 文章
@@ -50,13 +89,16 @@ func SetupRoutes() *gin.Engine {
 		{
 			articles.GET("", articleController.GetArticles)
 			articles.GET("/:id", articleController.GetArticle)
-			articles.POST("/:id/like", articleController.LikeArticle)
+			articles.GET("/:id/comments", commentController.GetTargetComments(models.CommentTargetArticle, "id"))
+			articles.POST("/:id/comments", verifiedCommenter, commentCaptcha, commentController.CreateTargetComment(models.CommentTargetArticle, "id"))
+			articles.POST("/:id/like", middleware.CaptchaMiddleware(captchaService, services.CaptchaScopeLike), articleController.LikeArticle)
 		}
 
 		// 分类
 		categories := api.Group("/categories")
 		{
 			categories.GET("", categoryController.GetCategories)
+			categories.GET("/tree", categoryController.GetCategoryTree)
 			categories.GET("/:id", categoryController.GetCategory)
 		}
 
@@ -64,22 +106,46 @@ func SetupRoutes() *gin.Engine {
 		tags := api.Group("/tags")
 		{
 			tags.GET("", tagController.GetTags)
+			tags.GET("/cloud", tagController.GetTagCloud)
+			tags.GET("/suggest", tagController.SuggestTags)
 		}
 
 		// 评论
 		comments := api.Group("/comments")
 		{
 			comments.GET("", commentController.GetComments)
-			comments.POST("", commentController.CreateComment)
+			comments.GET("/form-token", commentController.GetFormToken)
+			comments.POST("", verifiedCommenter, commentCaptcha, commentController.CreateComment)
+			// 评论者凭 ?token= 编辑令牌修改或撤回评论；不带令牌的删除仍需登录
+			comments.PUT("/:id", commentController.EditComment)
+			comments.DELETE("/:id", middleware.AuthUnlessQuery("token"), commentController.DeleteComment)
+		}
+
+		// 留言板
+		guestbook := api.Group("/guestbook")
+		{
+			guestbook.GET("", commentController.GetTargetComments(models.CommentTargetGuestbook, ""))
+			guestbook.POST("", verifiedCommenter, commentCaptcha, commentController.CreateTargetComment(models.CommentTargetGuestbook, ""))
+		}
+
+		// 邮件通知退订
+		notifications := api.Group("/notifications")
+		{
+			notifications.GET("/unsubscribe", notificationController.ConfirmUnsubscribe)
+			notifications.POST("/unsubscribe", notificationController.Unsubscribe)
 		}
 
-			// 音乐
+		// 音乐
 		music := api.Group("/music")
 		{
 			music.GET("", musicController.GetMusics)
 			music.GET("/:id", musicController.GetMusic)
 			music.GET("/playlists", musicController.GetPlaylists)
 			music.GET("/playlists/:id", musicController.GetPlaylist)
+			music.GET("/:id/comments", commentController.GetTargetComments(models.CommentTargetMusic, "id"))
+			music.POST("/:id/comments", verifiedCommenter, commentCaptcha, commentController.CreateTargetComment(models.CommentTargetMusic, "id"))
+			music.GET("/playlists/:id/comments", commentController.GetTargetComments(models.CommentTargetPlaylist, "id"))
+			music.POST("/playlists/:id/comments", verifiedCommenter, commentCaptcha, commentController.CreateTargetComment(models.CommentTargetPlaylist, "id"))
 		}
 
 		// 友情链接
@@ -94,6 +160,8 @@ func SetupRoutes() *gin.Engine {
 			labs.GET("", labController.GetLabs)
 			labs.GET("/:slug", labController.GetLab)
 			labs.GET("/:slug/articles", labController.GetLabArticles)
+			labs.GET("/:slug/comments", commentController.GetTargetComments(models.CommentTargetLab, "slug"))
+			labs.POST("/:slug/comments", verifiedCommenter, commentCaptcha, commentController.CreateTargetComment(models.CommentTargetLab, "slug"))
 		}
 	}
 
@@ -103,46 +171,82 @@ func SetupRoutes() *gin.Engine {
 	{
 		// 用户信息
 		authenticated.GET("/auth/profile", authController.GetProfile)
+		authenticated.PUT("/auth/password", authController.ChangePassword)
+		authenticated.GET("/auth/2fa", authController.TwoFactorStatus)
+		authenticated.POST("/auth/2fa/setup", authController.TwoFactorSetup)
+		authenticated.POST("/auth/2fa/confirm", authController.TwoFactorConfirm)
+		authenticated.POST("/auth/2fa/disable", authController.TwoFactorDisable)
+		authenticated.POST("/auth/2fa/recovery-codes", authController.RegenerateRecoveryCodes)
+		authenticated.POST("/auth/oauth/:provider/link", authController.OAuthLink)
+		authenticated.GET("/auth/identities", authController.ListIdentities)
+		authenticated.DELETE("/auth/identities/:id", authController.UnlinkIdentity)
 
 		// 文章管理
-		authenticated.POST("/articles", articleController.CreateArticle)
-		authenticated.PUT("/articles/:id", articleController.UpdateArticle)
-		authenticated.DELETE("/articles/:id", articleController.DeleteArticle)
+		// 修改、删除他人的文章还需要 article.manage，由控制器检查
+		authenticated.POST("/articles", verifiedAuthor, canWriteArticles, articleController.CreateArticle)
+		authenticated.PUT("/articles/:id", verifiedAuthor, canWriteArticles, articleController.UpdateArticle)
+		authenticated.PUT("/articles/:id/comment-settings", canWriteArticles, articleController.UpdateCommentSettings)
+		authenticated.DELETE("/articles/:id", canWriteArticles, articleController.DeleteArticle)
 
 		// 分类管理
-		authenticated.POST("/categories", categoryController.CreateCategory)
-		authenticated.PUT("/categories/:id", categoryController.UpdateCategory)
-		authenticated.DELETE("/categories/:id", categoryController.DeleteCategory)
+		authenticated.POST("/categories", canManageTaxonomy, categoryController.CreateCategory)
+		authenticated.PUT("/categories/:id", canManageTaxonomy, categoryController.UpdateCategory)
+		authenticated.DELETE("/categories/:id", canManageTaxonomy, categoryController.DeleteCategory)
 
 		// 标签管理
-		authenticated.POST("/tags", tagController.CreateTag)
-		authenticated.DELETE("/tags/:id", tagController.DeleteTag)
+		authenticated.POST("/tags", canManageTaxonomy, tagController.CreateTag)
+		authenticated.PUT("/tags/:id", canManageTaxonomy, tagController.UpdateTag)
+		authenticated.DELETE("/tags/:id", canManageTaxonomy, tagController.DeleteTag)
 
 		// 评论管理
-		authenticated.PUT("/comments/:id/status", commentController.UpdateCommentStatus)
-		authenticated.DELETE("/comments/:id", commentController.DeleteComment)
-		authenticated.GET("/comments/pending", commentController.GetPendingComments)
+		authenticated.PUT("/comments/:id/status", canModerate, commentController.UpdateCommentStatus)
+		authenticated.POST("/comments/bulk", canModerate, commentController.BulkModerateComments)
+		authenticated.GET("/comments/pending", canModerate, commentController.GetPendingComments)
+		authenticated.GET("/comments/:id/revisions", canModerate, commentController.GetCommentRevisions)
 
 		// 友情链接管理
-		authenticated.POST("/links", linkController.CreateLink)
-		authenticated.PUT("/links/:id", linkController.UpdateLink)
-		authenticated.DELETE("/links/:id", linkController.DeleteLink)
+		authenticated.POST("/links", canManageLinks, linkController.CreateLink)
+		authenticated.PUT("/links/:id", canManageLinks, linkController.UpdateLink)
+		authenticated.DELETE("/links/:id", canManageLinks, linkController.DeleteLink)
 
 		// 文件上传（需要认证）
-		authenticated.POST("/upload/file", uploadController.UploadFile)
-		authenticated.POST("/upload/image", uploadController.UploadImage)
-		authenticated.DELETE("/upload/:filename", uploadController.DeleteFile)
+		authenticated.POST("/upload/file", verifiedAuthor, canUpload, uploadController.UploadFile)
+		authenticated.POST("/upload/image", verifiedAuthor, canUpload, uploadController.UploadImage)
+		authenticated.DELETE("/upload/:filename", canManageMedia, uploadController.DeleteFile)
 	}
 
-	// 管理员路由
+	// 管理路由，各接口按权限开放给对应角色
 	admin := api.Group("/admin")
 	admin.Use(middleware.AuthMiddleware())
-	admin.Use(middleware.AdminMiddleware())
 	{
 		// 音乐管理
-		admin.POST("/music", musicController.CreateMusic)
-		admin.PUT("/music/:id", musicController.UpdateMusic)
-		admin.DELETE("/music/:id", musicController.DeleteMusic)
+		admin.POST("/music", canManageMusic, musicController.CreateMusic)
+		admin.PUT("/music/:id", canManageMusic, musicController.UpdateMusic)
+		admin.DELETE("/music/:id", canManageMusic, musicController.DeleteMusic)
+
+		// 标签合并、重命名与别名
+		admin.POST("/tags/merge", canManageTaxonomy, tagController.MergeTags)
+		admin.PUT("/tags/:id/rename", canManageTaxonomy, tagController.RenameTag)
+		admin.GET("/tags/:id/aliases", canManageTaxonomy, tagController.GetTagAliases)
+		admin.POST("/tags/:id/aliases", canManageTaxonomy, tagController.AddTagAlias)
+		admin.DELETE("/tags/aliases/:alias_id", canManageTaxonomy, tagController.DeleteTagAlias)
+
+		// 评论管理（完整记录）
+		admin.GET("/comments", canModerate, commentController.GetAllComments)
+		admin.POST("/comments/import", canManageSite, commentImportController.ImportComments)
+
+		// 内容链接检查
+		admin.GET("/link-check", canManageSite, linkCheckController.CheckLinks)
+
+		// 用户与角色管理
+		admin.GET("/roles", canManageUsers, userController.GetRoles)
+		admin.GET("/users", canManageUsers, userController.ListUsers)
+		admin.GET("/users/:id", canManageUsers, userController.GetUser)
+		admin.GET("/users/:id/articles", canManageUsers, userController.GetUserArticles)
+		admin.PUT("/users/:id/role", canManageUsers, userController.AssignRole)
+		admin.POST("/users/:id/disable", canManageUsers, userController.DisableUser)
+		admin.POST("/users/:id/enable", canManageUsers, userController.EnableUser)
+		admin.POST("/users/:id/password-reset", canManageUsers, userController.ForcePasswordReset)
 	}
 
 	// 静态文件服务（使用配置中的路径）
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// akismetChecker 使用 Akismet comment-check 协议，endpoint 可指向本地兼容服务
+type akismetChecker struct {
+	endpoint string
+	apiKey   string
+	blog     string
+	client   *http.Client
+}
+
+// NewAkismetChecker client 为 nil 时使用默认超时的客户端
+func NewAkismetChecker(endpoint, apiKey, blog string, client *http.Client) SpamChecker {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &akismetChecker{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		apiKey:   apiKey,
+		blog:     blog,
+		client:   client,
+	}
+}
+
+func (c *akismetChecker) Name() string { return "akismet" }
+
+func (c *akismetChecker) Check(sub *SpamSubmission) (SpamResult, error) {
+	comment := sub.Comment
+	form := url.Values{}
+	form.Set("api_key", c.apiKey)
+	form.Set("blog", c.blog)
+	form.Set("user_ip", comment.IP)
+	form.Set("user_agent", sub.UserAgent)
+	form.Set("referrer", sub.Referrer)
+	form.Set("comment_type", "comment")
+	form.Set("comment_author", comment.Author)
+	form.Set("comment_author_email", comment.Email)
+	form.Set("comment_author_url", comment.Website)
+	form.Set("comment_content", comment.Content)
+	if sub.Permalink != "" {
+		form.Set("permalink", sub.Permalink)
+	}
+
+	resp, err := c.client.PostForm(c.endpoint+"/1.1/comment-check", form)
+	if err != nil {
+		return SpamResult{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return SpamResult{}, err
+	}
+
+	switch strings.TrimSpace(string(body)) {
+	case "true":
+		if resp.Header.Get("X-akismet-pro-tip") == "discard" {
+			return SpamResult{Score: spamScoreAkismetDrop, Reasons: []string{"flagged as blatant spam"}}, nil
+		}
+		return SpamResult{Score: spamScoreAkismet, Reasons: []string{"flagged as spam"}}, nil
+	case "false":
+		return SpamResult{}, nil
+	default:
+		// 请求无效时 Akismet 返回 "invalid" 并在 X-akismet-debug-help 中说明原因
+		return SpamResult{}, fmt.Errorf("unexpected response %q: %s", strings.TrimSpace(string(body)), resp.Header.Get("X-akismet-debug-help"))
+	}
+}
@@ -3,46 +3,139 @@ package services
 import (
 	"blog-system/models"
 	"blog-system/repositories"
+	"errors"
 	"time"
 
-	"github.com/gosimple/slug"
 	"strconv"
 )
 
+var (
+	ErrInvalidCommentMode   = errors.New("comment mode must be one of open, moderated, closed")
+	ErrInvalidAutoCloseDays = errors.New("auto_close_days must not be negative")
+)
+
 type ArticleService interface {
 	GetArticles(page, pageSize int, filters map[string]interface{}) ([]models.Article, int64, int, int, error)
 	GetArticle(id string) (*models.Article, error)
 	GetArticleBySlug(slug string) (*models.Article, error)
 	CreateArticle(input *models.Article, tagIDs []uint) (*models.Article, error)
-	UpdateArticle(id string, input *models.Article, tagIDs []uint) (*models.Article, error)
+	// seo 为 nil 时保留原有 SEO 信息，否则整体替换
+	UpdateArticle(id string, input *models.Article, tagIDs []uint, seo *models.SEO) (*models.Article, error)
+	UpdateCommentSettings(id string, mode string, autoCloseDays int) (*models.Article, error)
 	DeleteArticle(id string) error
 	IncrementViews(id string) error
 	LikeArticle(id string) (int, error)
 }
 
 type articleService struct {
-	articleRepo repositories.ArticleRepository
-	tagRepo     repositories.TagRepository
+	articleRepo  repositories.ArticleRepository
+	tagRepo      repositories.TagRepository
+	categoryRepo repositories.CategoryRepository
+	slugService  SlugService
 }
 
 func NewArticleService() ArticleService {
 	return &articleService{
-		articleRepo: repositories.NewArticleRepository(),
-		tagRepo:     repositories.NewTagRepository(),
+		articleRepo:  repositories.NewArticleRepository(),
+		tagRepo:      repositories.NewTagRepository(),
+		categoryRepo: repositories.NewCategoryRepository(),
+		slugService:  NewSlugService(),
 	}
 }
 
 func (s *articleService) GetArticles(page, pageSize int, filters map[string]interface{}) ([]models.Article, int64, int, int, error) {
+	// 按分类筛选时包含子孙分类
+	if include, ok := filters["include_descendants"]; ok && include == true {
+		if categoryID, ok := filters["category_id"].(string); ok && categoryID != "" {
+			if id, err := strconv.ParseUint(categoryID, 10, 64); err == nil {
+				categories, err := s.categoryRepo.FindAll()
+				if err != nil {
+					return nil, 0, page, pageSize, err
+				}
+				filters["category_ids"] = descendantIDs(categories, uint(id))
+			}
+		}
+	}
+
+	// 标签别名解析为规范标签
+	if tagSlug, ok := filters["tag_slug"].(string); ok && tagSlug != "" {
+		filters["tag_slug"] = s.resolveTagSlug(tagSlug)
+	}
+
 	articles, total, err := s.articleRepo.FindAll(page, pageSize, filters)
-	return articles, total, page, pageSize, err
+	if err != nil {
+		return articles, total, page, pageSize, err
+	}
+	ids := make([]uint, 0, len(articles))
+	for _, article := range articles {
+		ids = append(ids, article.CategoryID)
+	}
+	byID := s.breadcrumbCategories(ids...)
+	for i := range articles {
+		articles[i].Breadcrumbs = categoryPath(byID, articles[i].CategoryID)
+	}
+	return articles, total, page, pageSize, nil
 }
 
 func (s *articleService) GetArticle(id string) (*models.Article, error) {
-	return s.articleRepo.FindByID(id)
+	article, err := s.articleRepo.FindByID(id)
+	if err != nil {
+		return article, err
+	}
+	article.Breadcrumbs = categoryPath(s.breadcrumbCategories(article.CategoryID), article.CategoryID)
+	applyArticleSEO(article)
+	return article, nil
+}
+
+func (s *articleService) resolveTagSlug(tagSlug string) string {
+	if _, err := s.tagRepo.FindBySlug(tagSlug); err == nil {
+		return tagSlug
+	}
+	alias, err := s.tagRepo.FindAliasBySlug(tagSlug)
+	if err != nil {
+		return tagSlug
+	}
+	tag, err := s.tagRepo.FindByID(alias.TagID)
+	if err != nil {
+		return tagSlug
+	}
+	return tag.Slug
+}
+
+func indexCategories(categories []models.Category) map[uint]models.Category {
+	byID := make(map[uint]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+	return byID
+}
+
+// breadcrumbCategories 只加载文章所属分类及其上级分类；查询失败时不显示面包屑
+func (s *articleService) breadcrumbCategories(categoryIDs ...uint) map[uint]models.Category {
+	ids := make([]uint, 0, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	categories, err := s.categoryRepo.FindWithAncestors(ids)
+	if err != nil {
+		return nil
+	}
+	return indexCategories(categories)
 }
 
 func (s *articleService) GetArticleBySlug(slug string) (*models.Article, error) {
-	return s.articleRepo.FindBySlug(slug)
+	article, err := s.articleRepo.FindBySlug(slug)
+	if err != nil {
+		return article, err
+	}
+	article.Breadcrumbs = categoryPath(s.breadcrumbCategories(article.CategoryID), article.CategoryID)
+	applyArticleSEO(article)
+	return article, nil
 }
 
 func (s *articleService) CreateArticle(input *models.Article, tagIDs []uint) (*models.Article, error) {
@@ -50,12 +143,8 @@ func (s *articleService) CreateArticle(input *models.Article, tagIDs []uint) (*m
 		input.Status = "draft"
 	}
 
-	articleSlug := slug.Make(input.Title)
-	count, _ := s.articleRepo.CountBySlug(articleSlug)
-	if count > 0 {
-		articleSlug = articleSlug + "-" + strconv.FormatInt(time.Now().Unix(), 10)
-	}
-	input.Slug = articleSlug
+	// input.Slug 为作者自定义的 slug，为空时按配置的策略生成
+	slugFn := s.slugService.Assign(input.Title, input.Slug, 0)
 
 	if input.Status == "published" {
 		now := time.Now()
@@ -68,19 +157,26 @@ func (s *articleService) CreateArticle(input *models.Article, tagIDs []uint) (*m
 		input.Tags = tags
 	}
 
-	err := s.articleRepo.Create(input)
+	err := s.articleRepo.CreateWithSlug(input, slugFn)
 	return input, err
 }
 
-func (s *articleService) UpdateArticle(id string, input *models.Article, tagIDs []uint) (*models.Article, error) {
+func (s *articleService) UpdateArticle(id string, input *models.Article, tagIDs []uint, seo *models.SEO) (*models.Article, error) {
 	article, err := s.articleRepo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
 
+	// 指定了新的自定义 slug，或标题变化时重新生成 slug
+	var slugFn repositories.SlugFunc
+	if input.Slug != "" && input.Slug != article.Slug {
+		slugFn = s.slugService.Assign("", input.Slug, article.ID)
+	} else if input.Slug == "" && input.Title != "" && input.Title != article.Title {
+		slugFn = s.slugService.Assign(input.Title, "", article.ID)
+	}
+
 	if input.Title != "" {
 		article.Title = input.Title
-		article.Slug = slug.Make(input.Title)
 	}
 	if input.Content != "" {
 		article.Content = input.Content
@@ -91,8 +187,10 @@ func (s *articleService) UpdateArticle(id string, input *models.Article, tagIDs
 	if input.CoverImage != "" {
 		article.CoverImage = input.CoverImage
 	}
-	if input.CategoryID != 0 {
+	if input.CategoryID != 0 && input.CategoryID != article.CategoryID {
 		article.CategoryID = input.CategoryID
+		// 清空已预加载的关联，避免 Save 时用旧分类覆盖 CategoryID
+		article.Category = models.Category{}
 	}
 	if input.Status != "" {
 		article.Status = input.Status
@@ -102,6 +200,9 @@ func (s *articleService) UpdateArticle(id string, input *models.Article, tagIDs
 		}
 	}
 	article.IsTop = input.IsTop
+	if seo != nil {
+		article.SEO = *seo
+	}
 
 	// Update Tags
 	if tagIDs != nil {
@@ -109,6 +210,28 @@ func (s *articleService) UpdateArticle(id string, input *models.Article, tagIDs
 		article.Tags = tags
 	}
 
+	if slugFn != nil {
+		err = s.articleRepo.UpdateWithSlug(article, slugFn)
+	} else {
+		err = s.articleRepo.Update(article)
+	}
+	return article, err
+}
+
+// UpdateCommentSettings 修改文章评论模式与自动关闭天数（0 表示使用站点默认值）
+func (s *articleService) UpdateCommentSettings(id string, mode string, autoCloseDays int) (*models.Article, error) {
+	if !models.ValidCommentMode(mode) {
+		return nil, ErrInvalidCommentMode
+	}
+	if autoCloseDays < 0 {
+		return nil, ErrInvalidAutoCloseDays
+	}
+	article, err := s.articleRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	article.CommentMode = mode
+	article.CommentAutoCloseDays = autoCloseDays
 	err = s.articleRepo.Update(article)
 	return article, err
 }
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, all sessions from this login have been revoked")
+)
+
+// SessionMeta 登录和刷新时的客户端信息，记录在刷新令牌上
+type SessionMeta struct {
+	IP        string
+	UserAgent string
+}
+
+// AuthTokens 登录、刷新成功后返回给客户端的令牌
+type AuthTokens struct {
+	AccessToken      string    `json:"token"`
+	ExpiresIn        int       `json:"expires_in"` // 访问令牌有效期（秒）
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// issueSession 开始新的登录会话，生成新的令牌家族
+func (s *userService) issueSession(user *models.User, meta SessionMeta) (*AuthTokens, error) {
+	familyID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	tokens, record, err := s.newTokens(user, familyID, meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokenRepo.Create(record); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// newTokens 签发访问令牌并生成同一家族的刷新令牌（尚未保存）
+func (s *userService) newTokens(user *models.User, familyID string, meta SessionMeta) (*AuthTokens, *models.RefreshToken, error) {
+	access, err := utils.GenerateToken(user.ID, user.Username, user.Role, user.TokenVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	refresh, err := randomHex(32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expiresAt := time.Now().Add(time.Duration(config.AppConfig.JWTRefreshTTLDays) * 24 * time.Hour)
+	record := &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashSecret(refresh),
+		ExpiresAt: expiresAt,
+		IP:        meta.IP,
+		UserAgent: truncate(meta.UserAgent, 255),
+	}
+	return &AuthTokens{
+		AccessToken:      access,
+		ExpiresIn:        int(utils.AccessTokenTTL().Seconds()),
+		RefreshToken:     refresh,
+		RefreshExpiresAt: expiresAt,
+	}, record, nil
+}
+
+func (s *userService) Refresh(refreshToken string, meta SessionMeta) (*AuthTokens, *models.User, error) {
+	record, err := s.tokenRepo.FindByHash(hashSecret(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	// 已经轮换过的令牌再次出现，说明令牌可能被盗，吊销整个家族
+	if record.ReplacedByID != nil {
+		if err := s.tokenRepo.RevokeFamily(record.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+	if record.RevokedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.repo.FindByID(record.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	if user.IsDisabled(time.Now()) {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	tokens, next, err := s.newTokens(user, record.FamilyID, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.tokenRepo.Rotate(record, next); err != nil {
+		// 并发刷新时令牌已被另一请求轮换
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+func (s *userService) Logout(refreshToken string, all bool) error {
+	record, err := s.tokenRepo.FindByHash(hashSecret(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	if all {
+		return s.tokenRepo.RevokeAllForUser(record.UserID)
+	}
+	return s.tokenRepo.RevokeFamily(record.FamilyID)
+}
+
+func (s *userService) RevokeSessions(userID uint) error {
+	return s.tokenRepo.RevokeAllForUser(userID)
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashSecret 令牌只保存 SHA-256 哈希，数据库泄露时无法直接使用
+func hashSecret(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/database"
+	"blog-system/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenRotationAndReuse(t *testing.T) {
+	setupTestDB(t, config.Config{})
+	service := NewUserService()
+	meta := SessionMeta{IP: "127.0.0.1", UserAgent: "test"}
+
+	// 两次登录得到两个令牌家族：first 用于轮换和重放，other 用于确认其他会话不受影响
+	tokens := map[string]string{}
+	for _, name := range []string{"first", "other"} {
+		session, _, err := service.Login("user", "user123", meta)
+		if err != nil {
+			t.Fatalf("Login() = %v", err)
+		}
+		tokens[name] = session.RefreshToken
+	}
+
+	steps := []struct {
+		name    string
+		present string // 提交的刷新令牌
+		saveAs  string // 成功时保存新令牌的名称
+		wantErr error
+	}{
+		{name: "rotate", present: "first", saveAs: "second"},
+		{name: "rotate again", present: "second", saveAs: "third"},
+		{name: "replay a rotated token", present: "first", wantErr: ErrRefreshTokenReused},
+		{name: "latest token of the family is revoked", present: "third", wantErr: ErrInvalidRefreshToken},
+		{name: "replay another rotated token", present: "second", wantErr: ErrRefreshTokenReused},
+		{name: "other login is unaffected", present: "other", saveAs: "other2"},
+		{name: "unknown token", present: "bogus", wantErr: ErrInvalidRefreshToken},
+	}
+	tokens["bogus"] = "not-a-token"
+
+	for _, step := range steps {
+		refreshed, _, err := service.Refresh(tokens[step.present], meta)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: Refresh() = %v, want %v", step.name, err, step.wantErr)
+		}
+		if err == nil {
+			if refreshed.RefreshToken == tokens[step.present] {
+				t.Fatalf("%s: refresh token was not rotated", step.name)
+			}
+			tokens[step.saveAs] = refreshed.RefreshToken
+		}
+	}
+}
+
+func TestRefreshRejectsDisabledAccount(t *testing.T) {
+	setupTestDB(t, config.Config{})
+	service := NewUserService()
+
+	session, user, err := service.Login("user", "user123", SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("disabled_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := service.Refresh(session.RefreshToken, SessionMeta{}); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("Refresh() = %v, want %v", err, ErrAccountDisabled)
+	}
+}
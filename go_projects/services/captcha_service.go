@@ -0,0 +1,232 @@
+package services
+
+import (
+	"blog-system/config"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 需要工作量证明的操作，题目只能用于签发时指定的操作
+const (
+	CaptchaScopeComment  = "comment"
+	CaptchaScopeRegister = "register"
+	CaptchaScopeLike     = "like"
+)
+
+// captchaVersion 题目格式版本：v1.scope.difficulty.expires.salt.signature
+const captchaVersion = "v1"
+
+// captchaMaxNonceLength 限制 nonce 长度，避免超长请求头参与哈希计算
+const captchaMaxNonceLength = 64
+
+var (
+	ErrInvalidCaptchaScope = errors.New("scope must be one of comment, register, like")
+	ErrCaptchaRequired     = errors.New("captcha required")
+	ErrCaptchaInvalid      = errors.New("invalid captcha solution")
+	ErrCaptchaExpired      = errors.New("captcha challenge expired")
+	ErrCaptchaReplayed     = errors.New("captcha challenge already used")
+)
+
+// CaptchaChallenge 下发给客户端的题目：找到 nonce 使 SHA-256(challenge + nonce) 的前 difficulty 位为 0
+type CaptchaChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Scope      string    `json:"scope"`
+	Difficulty int       `json:"difficulty"`
+	Algorithm  string    `json:"algorithm"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type CaptchaService interface {
+	Enabled() bool
+	// Issue 签发题目，难度随该 IP 近期的请求次数提高
+	Issue(scope, ip string) (*CaptchaChallenge, error)
+	// Verify 校验签名、有效期和答案，通过后题目作废
+	Verify(scope, challenge, nonce string) error
+}
+
+type captchaService struct {
+	mu        sync.Mutex
+	used      map[string]time.Time // 已使用的题目签名 -> 过期时间
+	hits      map[string]*captchaRate
+	lastSweep time.Time
+}
+
+// captchaRate 单个 IP 在当前统计窗口内的请求次数
+type captchaRate struct {
+	windowStart time.Time
+	count       int
+}
+
+var (
+	defaultCaptchaService     *captchaService
+	defaultCaptchaServiceOnce sync.Once
+)
+
+// NewCaptchaService 返回进程内共享的实例，防重放和限频记录保存在内存中
+func NewCaptchaService() CaptchaService {
+	defaultCaptchaServiceOnce.Do(func() {
+		defaultCaptchaService = &captchaService{
+			used: make(map[string]time.Time),
+			hits: make(map[string]*captchaRate),
+		}
+	})
+	return defaultCaptchaService
+}
+
+func ValidCaptchaScope(scope string) bool {
+	switch scope {
+	case CaptchaScopeComment, CaptchaScopeRegister, CaptchaScopeLike:
+		return true
+	}
+	return false
+}
+
+func (s *captchaService) Enabled() bool {
+	return config.AppConfig.CaptchaEnabled
+}
+
+func (s *captchaService) Issue(scope, ip string) (*CaptchaChallenge, error) {
+	if !ValidCaptchaScope(scope) {
+		return nil, ErrInvalidCaptchaScope
+	}
+
+	salt := make([]byte, 12)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	difficulty := s.difficultyFor(ip, now)
+	expiresAt := now.Add(time.Duration(config.AppConfig.CaptchaTTLSeconds) * time.Second)
+	payload := strings.Join([]string{
+		captchaVersion,
+		scope,
+		strconv.Itoa(difficulty),
+		strconv.FormatInt(expiresAt.Unix(), 10),
+		hex.EncodeToString(salt),
+	}, ".")
+
+	return &CaptchaChallenge{
+		Challenge:  payload + "." + captchaSignature(payload),
+		Scope:      scope,
+		Difficulty: difficulty,
+		Algorithm:  "SHA-256",
+		ExpiresAt:  time.Unix(expiresAt.Unix(), 0),
+	}, nil
+}
+
+func (s *captchaService) Verify(scope, challenge, nonce string) error {
+	if challenge == "" || nonce == "" {
+		return ErrCaptchaRequired
+	}
+	if len(nonce) > captchaMaxNonceLength {
+		return ErrCaptchaInvalid
+	}
+
+	sep := strings.LastIndex(challenge, ".")
+	if sep < 0 {
+		return ErrCaptchaInvalid
+	}
+	payload, signature := challenge[:sep], challenge[sep+1:]
+	if !hmac.Equal([]byte(signature), []byte(captchaSignature(payload))) {
+		return ErrCaptchaInvalid
+	}
+
+	// 签名有效，字段由本服务生成，只需按格式读取
+	parts := strings.Split(payload, ".")
+	if len(parts) != 5 || parts[0] != captchaVersion || parts[1] != scope {
+		return ErrCaptchaInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return ErrCaptchaInvalid
+	}
+	expires, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return ErrCaptchaInvalid
+	}
+	expiresAt := time.Unix(expires, 0)
+	now := time.Now()
+	if now.After(expiresAt) {
+		return ErrCaptchaExpired
+	}
+
+	sum := sha256.Sum256([]byte(challenge + nonce))
+	if leadingZeroBits(sum[:]) < difficulty {
+		return ErrCaptchaInvalid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	if _, ok := s.used[signature]; ok {
+		return ErrCaptchaReplayed
+	}
+	s.used[signature] = expiresAt
+	return nil
+}
+
+// difficultyFor 记录一次请求并计算难度：窗口内每满 rate_threshold 次，难度加 1
+func (s *captchaService) difficultyFor(ip string, now time.Time) int {
+	window := time.Duration(config.AppConfig.CaptchaRateWindowSeconds) * time.Second
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	rate, ok := s.hits[ip]
+	if !ok || now.Sub(rate.windowStart) >= window {
+		rate = &captchaRate{windowStart: now}
+		s.hits[ip] = rate
+	}
+	rate.count++
+
+	difficulty := config.AppConfig.CaptchaDifficulty + (rate.count-1)/config.AppConfig.CaptchaRateThreshold
+	if limit := config.AppConfig.CaptchaMaxDifficulty; difficulty > limit {
+		difficulty = limit
+	}
+	return difficulty
+}
+
+// sweep 定期清理已过期的题目和统计窗口，调用方需持有锁
+func (s *captchaService) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for signature, expiresAt := range s.used {
+		if now.After(expiresAt) {
+			delete(s.used, signature)
+		}
+	}
+	window := time.Duration(config.AppConfig.CaptchaRateWindowSeconds) * time.Second
+	for ip, rate := range s.hits {
+		if now.Sub(rate.windowStart) >= window {
+			delete(s.hits, ip)
+		}
+	}
+}
+
+func captchaSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte("captcha:"+config.AppConfig.JWTSecret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func leadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
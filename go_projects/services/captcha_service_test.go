@@ -0,0 +1,191 @@
+package services
+
+import (
+	"blog-system/config"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCaptchaService(t *testing.T, cfg config.Config) *captchaService {
+	t.Helper()
+	cfg.JWTSecret = "test-secret"
+	cfg.CaptchaEnabled = true
+	config.AppConfig = &cfg
+	return &captchaService{used: make(map[string]time.Time), hits: make(map[string]*captchaRate)}
+}
+
+// solveCaptcha 暴力求解；wantValid 为 false 时返回一个不满足难度的 nonce
+func solveCaptcha(t *testing.T, challenge *CaptchaChallenge, wantValid bool) string {
+	t.Helper()
+	for i := 0; i < 1<<24; i++ {
+		nonce := strconv.Itoa(i)
+		sum := sha256.Sum256([]byte(challenge.Challenge + nonce))
+		if (leadingZeroBits(sum[:]) >= challenge.Difficulty) == wantValid {
+			return nonce
+		}
+	}
+	t.Fatal("no nonce found")
+	return ""
+}
+
+func TestCaptchaVerify(t *testing.T) {
+	defaults := config.Config{
+		CaptchaDifficulty:        6,
+		CaptchaMaxDifficulty:     10,
+		CaptchaTTLSeconds:        300,
+		CaptchaRateWindowSeconds: 600,
+		CaptchaRateThreshold:     100,
+	}
+
+	tests := []struct {
+		name    string
+		ttl     int
+		mutate  func(t *testing.T, c *CaptchaChallenge) (scope, challenge, nonce string)
+		wantErr error
+	}{
+		{
+			name: "valid solution",
+			mutate: func(t *testing.T, c *CaptchaChallenge) (string, string, string) {
+				return CaptchaScopeComment, c.Challenge, solveCaptcha(t, c, true)
+			},
+		},
+		{
+			name: "missing headers",
+			mutate: func(t *testing.T, c *CaptchaChallenge) (string, string, string) {
+				return CaptchaScopeComment, "", ""
+			},
+			wantErr: ErrCaptchaRequired,
+		},
+		{
+			name: "wrong answer",
+			mutate: func(t *testing.T, c *CaptchaChallenge) (string, string, string) {
+				return CaptchaScopeComment, c.Challenge, solveCaptcha(t, c, false)
+			},
+			wantErr: ErrCaptchaInvalid,
+		},
+		{
+			name: "challenge issued for another scope",
+			mutate: func(t *testing.T, c *CaptchaChallenge) (string, string, string) {
+				return CaptchaScopeRegister, c.Challenge, solveCaptcha(t, c, true)
+			},
+			wantErr: ErrCaptchaInvalid,
+		},
+		{
+			name: "lowered difficulty breaks the signature",
+			mutate: func(t *testing.T, c *CaptchaChallenge) (string, string, string) {
+				forged := *c
+				forged.Challenge = strings.Replace(c.Challenge, "."+strconv.Itoa(c.Difficulty)+".", ".0.", 1)
+				forged.Difficulty = 0
+				return CaptchaScopeComment, forged.Challenge, solveCaptcha(t, &forged, true)
+			},
+			wantErr: ErrCaptchaInvalid,
+		},
+		{
+			name: "oversized nonce",
+			mutate: func(t *testing.T, c *CaptchaChallenge) (string, string, string) {
+				return CaptchaScopeComment, c.Challenge, strings.Repeat("1", captchaMaxNonceLength+1)
+			},
+			wantErr: ErrCaptchaInvalid,
+		},
+		{
+			name: "expired challenge",
+			ttl:  -1,
+			mutate: func(t *testing.T, c *CaptchaChallenge) (string, string, string) {
+				return CaptchaScopeComment, c.Challenge, solveCaptcha(t, c, true)
+			},
+			wantErr: ErrCaptchaExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaults
+			if tt.ttl != 0 {
+				cfg.CaptchaTTLSeconds = tt.ttl
+			}
+			service := newTestCaptchaService(t, cfg)
+			challenge, err := service.Issue(CaptchaScopeComment, "203.0.113.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			scope, token, nonce := tt.mutate(t, challenge)
+			if err := service.Verify(scope, token, nonce); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCaptchaReplay(t *testing.T) {
+	service := newTestCaptchaService(t, config.Config{
+		CaptchaDifficulty:        6,
+		CaptchaMaxDifficulty:     10,
+		CaptchaTTLSeconds:        300,
+		CaptchaRateWindowSeconds: 600,
+		CaptchaRateThreshold:     100,
+	})
+	challenge, err := service.Issue(CaptchaScopeLike, "203.0.113.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := solveCaptcha(t, challenge, true)
+
+	if err := service.Verify(CaptchaScopeLike, challenge.Challenge, nonce); err != nil {
+		t.Fatalf("first Verify() = %v", err)
+	}
+	if err := service.Verify(CaptchaScopeLike, challenge.Challenge, nonce); !errors.Is(err, ErrCaptchaReplayed) {
+		t.Errorf("replayed Verify() = %v, want %v", err, ErrCaptchaReplayed)
+	}
+
+	// 同一道题换一个同样有效的 nonce 也不能再次使用
+	first, _ := strconv.Atoi(nonce)
+	for i := first + 1; ; i++ {
+		sum := sha256.Sum256([]byte(challenge.Challenge + strconv.Itoa(i)))
+		if leadingZeroBits(sum[:]) < challenge.Difficulty {
+			continue
+		}
+		if err := service.Verify(CaptchaScopeLike, challenge.Challenge, strconv.Itoa(i)); !errors.Is(err, ErrCaptchaReplayed) {
+			t.Errorf("Verify() with another valid nonce = %v, want %v", err, ErrCaptchaReplayed)
+		}
+		break
+	}
+}
+
+func TestCaptchaDifficultyRaisedPerIP(t *testing.T) {
+	service := newTestCaptchaService(t, config.Config{
+		CaptchaDifficulty:        4,
+		CaptchaMaxDifficulty:     6,
+		CaptchaTTLSeconds:        300,
+		CaptchaRateWindowSeconds: 600,
+		CaptchaRateThreshold:     2,
+	})
+
+	tests := []struct {
+		ip   string
+		want int
+	}{
+		{"198.51.100.1", 4},
+		{"198.51.100.1", 4},
+		{"198.51.100.1", 5},
+		{"198.51.100.2", 4}, // 其他 IP 不受影响
+		{"198.51.100.1", 5},
+		{"198.51.100.1", 6},
+		{"198.51.100.1", 6},
+		{"198.51.100.1", 6}, // 不超过上限
+	}
+
+	for i, tt := range tests {
+		challenge, err := service.Issue(CaptchaScopeComment, tt.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if challenge.Difficulty != tt.want {
+			t.Errorf("request %d from %s: difficulty %d, want %d", i+1, tt.ip, challenge.Difficulty, tt.want)
+		}
+	}
+}
@@ -3,57 +3,266 @@ package services
 import (
 	"blog-system/models"
 	"blog-system/repositories"
-	"github.com/gosimple/slug"
+	"errors"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCategoryParentNotFound = errors.New("parent category not found")
+	ErrCategoryCycle          = errors.New("category cannot be moved under itself or its descendants")
+	ErrCategoryHasArticles    = errors.New("category still has articles, specify reassign_to to move them")
+	ErrCategoryReassignTarget = errors.New("reassign_to must be another existing category")
 )
 
+// CategoryTreeNode 分类树节点，ArticleCount 包含所有子孙分类的已发布文章数
+type CategoryTreeNode struct {
+	ID           uint                `json:"id"`
+	Name         string              `json:"name"`
+	Slug         string              `json:"slug"`
+	Description  string              `json:"description"`
+	ParentID     *uint               `json:"parent_id"`
+	ArticleCount int64               `json:"article_count"`
+	Children     []*CategoryTreeNode `json:"children"`
+}
+
+// CategoryFields 更新分类时请求中显式出现的可选字段
+type CategoryFields struct {
+	Parent bool // parent_id，包括 null（移到顶层）
+	SEO    bool // seo
+}
+
 type CategoryService interface {
 	GetCategories() ([]models.Category, error)
+	GetCategoriesWithStats() ([]models.CategoryWithStats, error)
 	GetCategory(id uint) (*models.Category, error)
+	GetCategoryTree() ([]*CategoryTreeNode, error)
+	GetDescendantIDs(id uint) ([]uint, error)
 	CreateCategory(input *models.Category) (*models.Category, error)
-	UpdateCategory(id uint, input *models.Category) (*models.Category, error)
-	DeleteCategory(id uint) error
+	// UpdateCategory fields 标明请求中出现了哪些可选字段，未出现的保留原值
+	UpdateCategory(id uint, input *models.Category, fields CategoryFields) (*models.Category, error)
+	DeleteCategory(id uint, reassignTo *uint) (int64, error)
 }
 
 type categoryService struct {
-	repo repositories.CategoryRepository
+	repo        repositories.CategoryRepository
+	slugService SlugService
 }
 
 func NewCategoryService() CategoryService {
-	return &categoryService{repo: repositories.NewCategoryRepository()}
+	return &categoryService{
+		repo:        repositories.NewCategoryRepository(),
+		slugService: NewSlugService(),
+	}
 }
 
 func (s *categoryService) GetCategories() ([]models.Category, error) {
 	return s.repo.FindAll()
 }
 
+func (s *categoryService) GetCategoriesWithStats() ([]models.CategoryWithStats, error) {
+	return s.repo.FindAllWithStats()
+}
+
 func (s *categoryService) GetCategory(id uint) (*models.Category, error) {
-	return s.repo.FindByID(id)
+	category, err := s.repo.FindByID(id)
+	if err != nil {
+		return category, err
+	}
+	applyCategorySEO(category)
+	return category, nil
+}
+
+func (s *categoryService) GetCategoryTree() ([]*CategoryTreeNode, error) {
+	categories, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountPublishedArticles()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+
+	nodes := make(map[uint]*CategoryTreeNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryTreeNode{
+			ID:          category.ID,
+			Name:        category.Name,
+			Slug:        category.Slug,
+			Description: category.Description,
+			ParentID:    category.ParentID,
+			Children:    []*CategoryTreeNode{},
+		}
+	}
+
+	roots := []*CategoryTreeNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok && *category.ParentID != category.ID {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		// 父分类不存在时作为根节点展示
+		roots = append(roots, node)
+	}
+
+	for _, root := range roots {
+		sumArticleCounts(root, counts)
+	}
+	return roots, nil
+}
+
+func sumArticleCounts(node *CategoryTreeNode, counts map[uint]int64) int64 {
+	total := counts[node.ID]
+	for _, child := range node.Children {
+		total += sumArticleCounts(child, counts)
+	}
+	node.ArticleCount = total
+	return total
+}
+
+func (s *categoryService) GetDescendantIDs(id uint) ([]uint, error) {
+	categories, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	return descendantIDs(categories, id), nil
+}
+
+// descendantIDs 返回 id 本身及其所有子孙分类的 ID
+func descendantIDs(categories []models.Category, id uint) []uint {
+	children := make(map[uint][]uint)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []uint{id}
+	visited := map[uint]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !visited[child] {
+				visited[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// categoryPath 返回从根分类到 id 的面包屑路径
+func categoryPath(byID map[uint]models.Category, id uint) []models.CategoryCrumb {
+	var path []models.CategoryCrumb
+	visited := make(map[uint]bool)
+	current, ok := byID[id]
+	for ok && !visited[current.ID] {
+		visited[current.ID] = true
+		path = append(path, models.CategoryCrumb{ID: current.ID, Name: current.Name, Slug: current.Slug})
+		if current.ParentID == nil {
+			break
+		}
+		current, ok = byID[*current.ParentID]
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// validateParent 校验父分类存在，且不会形成环
+func (s *categoryService) validateParent(id uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if id != 0 && *parentID == id {
+		return ErrCategoryCycle
+	}
+
+	categories, err := s.repo.FindAll()
+	if err != nil {
+		return err
+	}
+	byID := indexCategories(categories)
+	if _, ok := byID[*parentID]; !ok {
+		return ErrCategoryParentNotFound
+	}
+
+	for _, crumb := range categoryPath(byID, *parentID) {
+		if crumb.ID == id {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
 }
 
 func (s *categoryService) CreateCategory(input *models.Category) (*models.Category, error) {
-	input.Slug = slug.Make(input.Name)
-	err := s.repo.Create(input)
+	if err := s.validateParent(0, input.ParentID); err != nil {
+		return nil, err
+	}
+	err := s.repo.CreateWithSlug(input, s.slugService.Assign(input.Name, input.Slug, 0))
 	return input, err
 }
 
-func (s *categoryService) UpdateCategory(id uint, input *models.Category) (*models.Category, error) {
+func (s *categoryService) UpdateCategory(id uint, input *models.Category, fields CategoryFields) (*models.Category, error) {
 	category, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	if !fields.Parent {
+		input.ParentID = category.ParentID
+	}
+	if err := s.validateParent(id, input.ParentID); err != nil {
+		return nil, err
+	}
+
+	var slugFn repositories.SlugFunc
+	if input.Slug != "" && input.Slug != category.Slug {
+		slugFn = s.slugService.Assign("", input.Slug, category.ID)
+	} else if input.Slug == "" && input.Name != category.Name {
+		slugFn = s.slugService.Assign(input.Name, "", category.ID)
+	}
 
 	category.Name = input.Name
-	category.Slug = slug.Make(input.Name)
 	category.Description = input.Description
+	category.ParentID = input.ParentID
+	if fields.SEO {
+		category.SEO = input.SEO
+	}
 
-	err = s.repo.Update(category)
+	if slugFn != nil {
+		err = s.repo.UpdateWithSlug(category, slugFn)
+	} else {
+		err = s.repo.Update(category)
+	}
 	return category, err
 }
 
-func (s *categoryService) DeleteCategory(id uint) error {
+// DeleteCategory 删除分类；分类下仍有文章时必须指定 reassignTo，返回迁移的文章数
+func (s *categoryService) DeleteCategory(id uint, reassignTo *uint) (int64, error) {
 	category, err := s.repo.FindByID(id)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	if reassignTo != nil {
+		if *reassignTo == category.ID {
+			return 0, ErrCategoryReassignTarget
+		}
+	}
+
+	moved, err := s.repo.Delete(category, reassignTo)
+	switch {
+	case errors.Is(err, repositories.ErrCategoryNotEmpty):
+		return 0, ErrCategoryHasArticles
+	case errors.Is(err, gorm.ErrRecordNotFound) && reassignTo != nil:
+		return 0, ErrCategoryReassignTarget
 	}
-	return s.repo.Delete(category)
+	return moved, err
 }
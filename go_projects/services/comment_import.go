@@ -0,0 +1,277 @@
+package services
+
+import (
+	"blog-system/models"
+	"blog-system/repositories"
+	"blog-system/utils"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支持导入的第三方评论系统
+const (
+	CommentImportDisqus = "disqus"
+	CommentImportTwikoo = "twikoo"
+	CommentImportWaline = "waline"
+)
+
+var (
+	ErrInvalidImportSource = errors.New("source must be one of disqus, twikoo, waline")
+	ErrImportFormat        = errors.New("failed to parse export file")
+)
+
+// importedComment 各导出格式解析后的统一结构
+type importedComment struct {
+	ID          string // 来源系统中的评论 ID
+	ParentID    string
+	Thread      string // 所在页面的 URL 或路径
+	Identifier  string // 页面的其他标识（如 Disqus thread id），URL 匹配不到时再尝试
+	ThreadTitle string
+	Author      string
+	Email       string
+	Website     string
+	IP          string
+	Content     string // Markdown 文本
+	Status      string
+	Deleted     bool
+	CreatedAt   time.Time
+}
+
+// UnmatchedThread 没能对应到文章的页面及其评论数
+type UnmatchedThread struct {
+	Thread   string `json:"thread"`
+	Title    string `json:"title,omitempty"`
+	Comments int    `json:"comments"`
+}
+
+// CommentImportReport 一次导入的结果
+type CommentImportReport struct {
+	Source    string            `json:"source"`
+	DryRun    bool              `json:"dry_run"`
+	Total     int               `json:"total"`
+	Imported  int               `json:"imported"`
+	Skipped   int               `json:"skipped"` // 之前已经导入过
+	Ignored   int               `json:"ignored"` // 已删除或没有内容的记录
+	Unmatched []UnmatchedThread `json:"unmatched"`
+}
+
+type CommentImportService interface {
+	// Import 解析导出文件并把评论导入到对应文章下；dryRun 时只生成报告不写入
+	Import(source, filename string, r io.Reader, dryRun bool) (*CommentImportReport, error)
+}
+
+type commentImportService struct {
+	repo        repositories.CommentRepository
+	articleRepo repositories.ArticleRepository
+}
+
+func NewCommentImportService() CommentImportService {
+	return &commentImportService{
+		repo:        repositories.NewCommentRepository(),
+		articleRepo: repositories.NewArticleRepository(),
+	}
+}
+
+func (s *commentImportService) Import(source, filename string, r io.Reader, dryRun bool) (*CommentImportReport, error) {
+	var items []importedComment
+	var err error
+	switch source {
+	case CommentImportDisqus:
+		items, err = parseDisqusExport(r)
+	case CommentImportTwikoo:
+		items, err = parseTwikooExport(r)
+	case CommentImportWaline:
+		items, err = parseWalineExport(filename, r)
+	default:
+		return nil, ErrInvalidImportSource
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+	}
+
+	matcher, err := s.newArticleMatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, 0, len(items))
+	for _, item := range items {
+		refs = append(refs, source+":"+item.ID)
+	}
+	existing, err := s.repo.FindByImportRefs(refs)
+	if err != nil {
+		return nil, err
+	}
+	// 导入标识到本地评论，包括之前导入过的评论，回复据此找到父评论
+	imported := make(map[string]models.Comment, len(items))
+	for _, comment := range existing {
+		imported[comment.ImportRef] = comment
+	}
+
+	report := &CommentImportReport{Source: source, DryRun: dryRun, Total: len(items), Unmatched: []UnmatchedThread{}}
+	unmatched := map[string]int{}
+
+	for _, item := range parentsFirst(items) {
+		ref := source + ":" + item.ID
+		if item.ID == "" || item.Deleted || strings.TrimSpace(item.Content) == "" {
+			report.Ignored++
+			continue
+		}
+		if _, ok := imported[ref]; ok {
+			report.Skipped++
+			continue
+		}
+
+		articleID, ok := matcher.match(item.Thread, item.Identifier)
+		if !ok {
+			thread := item.Thread
+			if thread == "" {
+				thread = item.Identifier
+			}
+			if i, seen := unmatched[thread]; seen {
+				report.Unmatched[i].Comments++
+			} else {
+				unmatched[thread] = len(report.Unmatched)
+				report.Unmatched = append(report.Unmatched, UnmatchedThread{Thread: thread, Title: item.ThreadTitle, Comments: 1})
+			}
+			continue
+		}
+
+		report.Imported++
+		if dryRun {
+			continue
+		}
+
+		comment := &models.Comment{
+			TargetType: models.CommentTargetArticle,
+			TargetID:   articleID,
+			Content:    strings.TrimSpace(item.Content),
+			Author:     item.Author,
+			Email:      item.Email,
+			Website:    item.Website,
+			IP:         item.IP,
+			Status:     item.Status,
+			CreatedAt:  item.CreatedAt,
+			UpdatedAt:  item.CreatedAt,
+			ImportRef:  ref,
+		}
+		if comment.Author == "" {
+			comment.Author = "Anonymous"
+		}
+		if !models.ValidCommentStatus(comment.Status) {
+			comment.Status = models.CommentStatusApproved
+		}
+		// 父评论必须在同一篇文章下，否则作为顶层评论导入
+		if parent, ok := imported[source+":"+item.ParentID]; ok && item.ParentID != "" && parent.TargetID == articleID {
+			comment.ParentID = &parent.ID
+		}
+		// 导入的历史评论不受长度和链接数限制，也不再经过反垃圾检查和通知
+		if comment.ContentHTML, _, err = utils.RenderCommentMarkdown(comment.Content); err != nil {
+			return report, err
+		}
+		if err := s.repo.Create(comment); err != nil {
+			return report, err
+		}
+		imported[ref] = *comment
+	}
+	return report, nil
+}
+
+// parentsFirst 按时间排序，并保证父评论排在回复之前
+func parentsFirst(items []importedComment) []importedComment {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+
+	index := make(map[string]int, len(items))
+	for i, item := range items {
+		index[item.ID] = i
+	}
+	ordered := make([]importedComment, 0, len(items))
+	visited := make([]bool, len(items))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		if parent, ok := index[items[i].ParentID]; ok && items[i].ParentID != "" {
+			visit(parent)
+		}
+		ordered = append(ordered, items[i])
+	}
+	for i := range items {
+		visit(i)
+	}
+	return ordered
+}
+
+// articleMatcher 根据页面 URL、路径或 slug 找到对应的文章
+type articleMatcher struct {
+	bySlug map[string]uint
+	byID   map[uint]bool
+}
+
+func (s *commentImportService) newArticleMatcher() (*articleMatcher, error) {
+	articles, err := s.articleRepo.FindSlugs()
+	if err != nil {
+		return nil, err
+	}
+	m := &articleMatcher{bySlug: make(map[string]uint, len(articles)), byID: make(map[uint]bool, len(articles))}
+	for _, article := range articles {
+		m.byID[article.ID] = true
+		if article.Slug != "" {
+			m.bySlug[strings.ToLower(article.Slug)] = article.ID
+		}
+	}
+	return m, nil
+}
+
+func (m *articleMatcher) match(candidates ...string) (uint, bool) {
+	for _, candidate := range candidates {
+		if id, ok := m.matchOne(candidate); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// matchOne 支持 /articles/:id 形式的本站地址，其余情况取路径最后一段作为 slug
+func (m *articleMatcher) matchOne(thread string) (uint, bool) {
+	path := strings.TrimSpace(thread)
+	if path == "" {
+		return 0, false
+	}
+	if u, err := url.Parse(path); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+
+	segments := strings.FieldsFunc(strings.ToLower(path), func(r rune) bool { return r == '/' })
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] != "articles" {
+			continue
+		}
+		if id, err := strconv.ParseUint(segments[i+1], 10, 64); err == nil && m.byID[uint(id)] {
+			return uint(id), true
+		}
+	}
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		slug := strings.TrimSuffix(strings.TrimSuffix(segments[i], ".html"), ".htm")
+		if slug == "index" || slug == "" {
+			continue
+		}
+		id, ok := m.bySlug[slug]
+		return id, ok
+	}
+	return 0, false
+}
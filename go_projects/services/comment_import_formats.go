@@ -0,0 +1,339 @@
+package services
+
+import (
+	"blog-system/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"html"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// disqusExport Disqus 导出的 XML，评论（post）通过 dsq:id 引用所在页面（thread）和父评论
+type disqusExport struct {
+	Threads []struct {
+		DsqID      string `xml:"http://disqus.com/disqus-internals id,attr"`
+		Identifier string `xml:"id"`
+		Link       string `xml:"link"`
+		Title      string `xml:"title"`
+	} `xml:"thread"`
+	Posts []struct {
+		DsqID     string `xml:"http://disqus.com/disqus-internals id,attr"`
+		Message   string `xml:"message"`
+		CreatedAt string `xml:"createdAt"`
+		IsDeleted bool   `xml:"isDeleted"`
+		IsSpam    bool   `xml:"isSpam"`
+		IPAddress string `xml:"ipAddress"`
+		Author    struct {
+			Name  string `xml:"name"`
+			Email string `xml:"email"`
+		} `xml:"author"`
+		Thread disqusRef  `xml:"thread"`
+		Parent *disqusRef `xml:"parent"`
+	} `xml:"post"`
+}
+
+type disqusRef struct {
+	DsqID string `xml:"http://disqus.com/disqus-internals id,attr"`
+}
+
+func parseDisqusExport(r io.Reader) ([]importedComment, error) {
+	var export disqusExport
+	if err := xml.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+
+	type thread struct{ link, identifier, title string }
+	threads := make(map[string]thread, len(export.Threads))
+	for _, t := range export.Threads {
+		threads[t.DsqID] = thread{link: t.Link, identifier: t.Identifier, title: t.Title}
+	}
+
+	items := make([]importedComment, 0, len(export.Posts))
+	for _, post := range export.Posts {
+		t := threads[post.Thread.DsqID]
+		item := importedComment{
+			ID:          post.DsqID,
+			Thread:      t.link,
+			Identifier:  t.identifier,
+			ThreadTitle: t.title,
+			Author:      post.Author.Name,
+			Email:       post.Author.Email,
+			IP:          post.IPAddress,
+			Content:     htmlToMarkdown(post.Message),
+			Status:      models.CommentStatusApproved,
+			Deleted:     post.IsDeleted,
+			CreatedAt:   parseImportTime(post.CreatedAt),
+		}
+		if post.Parent != nil {
+			item.ParentID = post.Parent.DsqID
+		}
+		if post.IsSpam {
+			item.Status = models.CommentStatusSpam
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+type twikooComment struct {
+	ID      flexString `json:"_id"`
+	Nick    string     `json:"nick"`
+	Mail    string     `json:"mail"`
+	Link    string     `json:"link"`
+	IP      string     `json:"ip"`
+	URL     string     `json:"url"`
+	Href    string     `json:"href"`
+	Comment string     `json:"comment"` // Twikoo 保存的是渲染后的 HTML
+	PID     flexString `json:"pid"`
+	IsSpam  bool       `json:"isSpam"`
+	Created flexString `json:"created"` // 毫秒时间戳
+}
+
+// parseTwikooExport 支持管理面板导出的 JSON 数组，以及云数据库导出的每行一个 JSON 对象
+func parseTwikooExport(r io.Reader) ([]importedComment, error) {
+	var records []twikooComment
+	if err := decodeJSONRecords(r, &records); err != nil {
+		return nil, err
+	}
+
+	items := make([]importedComment, 0, len(records))
+	for _, record := range records {
+		item := importedComment{
+			ID:         string(record.ID),
+			ParentID:   string(record.PID),
+			Thread:     record.URL,
+			Identifier: record.Href,
+			Author:     record.Nick,
+			Email:      record.Mail,
+			Website:    record.Link,
+			IP:         record.IP,
+			Content:    htmlToMarkdown(record.Comment),
+			Status:     models.CommentStatusApproved,
+			CreatedAt:  parseImportTime(string(record.Created)),
+		}
+		if record.IsSpam {
+			item.Status = models.CommentStatusSpam
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+type walineComment struct {
+	ObjectID   flexString `json:"objectId"`
+	ID         flexString `json:"id"` // MySQL/PostgreSQL 存储使用自增 id
+	Nick       string     `json:"nick"`
+	Mail       string     `json:"mail"`
+	Link       string     `json:"link"`
+	IP         string     `json:"ip"`
+	URL        string     `json:"url"`
+	Comment    string     `json:"comment"` // Markdown 原文
+	PID        flexString `json:"pid"`
+	Status     string     `json:"status"`
+	InsertedAt flexString `json:"insertedAt"`
+	CreatedAt  flexString `json:"createdAt"`
+}
+
+var walineStatus = map[string]string{
+	"approved": models.CommentStatusApproved,
+	"waiting":  models.CommentStatusPending,
+	"spam":     models.CommentStatusSpam,
+}
+
+// parseWalineExport 支持管理面板导出的 JSON（{"data":{"Comment":[...]}}）和 Comment 表的 CSV
+func parseWalineExport(filename string, r io.Reader) ([]importedComment, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []walineComment
+	trimmed := bytes.TrimSpace(data)
+	if strings.EqualFold(filepath.Ext(filename), ".csv") || (len(trimmed) > 0 && trimmed[0] != '{' && trimmed[0] != '[') {
+		records, err = parseWalineCSV(data)
+	} else if len(trimmed) > 0 && trimmed[0] == '{' {
+		var export struct {
+			Data struct {
+				Comment []walineComment `json:"Comment"`
+			} `json:"data"`
+		}
+		err = json.Unmarshal(trimmed, &export)
+		records = export.Data.Comment
+	} else {
+		err = json.Unmarshal(trimmed, &records)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]importedComment, 0, len(records))
+	for _, record := range records {
+		id := string(record.ObjectID)
+		if id == "" {
+			id = string(record.ID)
+		}
+		created := string(record.InsertedAt)
+		if created == "" {
+			created = string(record.CreatedAt)
+		}
+		status, ok := walineStatus[record.Status]
+		if !ok {
+			status = models.CommentStatusApproved
+		}
+		items = append(items, importedComment{
+			ID:        id,
+			ParentID:  string(record.PID),
+			Thread:    record.URL,
+			Author:    record.Nick,
+			Email:     record.Mail,
+			Website:   record.Link,
+			IP:        record.IP,
+			Content:   record.Comment,
+			Status:    status,
+			CreatedAt: parseImportTime(created),
+		})
+	}
+	return items, nil
+}
+
+// parseWalineCSV 按表头列名读取，列的顺序和多余的列不影响
+func parseWalineCSV(data []byte) ([]walineComment, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	header := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		header[strings.TrimSpace(name)] = i
+	}
+	column := func(row []string, name string) string {
+		if i, ok := header[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	records := make([]walineComment, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		records = append(records, walineComment{
+			ObjectID:   flexString(column(row, "objectId")),
+			ID:         flexString(column(row, "id")),
+			Nick:       column(row, "nick"),
+			Mail:       column(row, "mail"),
+			Link:       column(row, "link"),
+			IP:         column(row, "ip"),
+			URL:        column(row, "url"),
+			Comment:    column(row, "comment"),
+			PID:        flexString(column(row, "pid")),
+			Status:     column(row, "status"),
+			InsertedAt: flexString(column(row, "insertedAt")),
+			CreatedAt:  flexString(column(row, "createdAt")),
+		})
+	}
+	return records, nil
+}
+
+// flexString 兼容字符串、数字以及 MongoDB 扩展 JSON（{"$oid": ...}、{"$date": ...}）
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || string(data) == "null":
+		*f = ""
+	case data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*f = flexString(s)
+	case data[0] == '{':
+		var wrapped map[string]flexString
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return err
+		}
+		*f = ""
+		for _, value := range wrapped {
+			*f = value
+		}
+	default:
+		*f = flexString(data)
+	}
+	return nil
+}
+
+// decodeJSONRecords 解析 JSON 数组或每行一个对象的 JSON Lines
+func decodeJSONRecords(r io.Reader, records interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, records)
+	}
+
+	var lines []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var line json.RawMessage
+		if err := decoder.Decode(&line); err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+	joined, err := json.Marshal(lines)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(joined, records)
+}
+
+// parseImportTime 解析 RFC 3339、常见日期格式或秒/毫秒时间戳，无法解析时返回零值（保存时使用当前时间）
+func parseImportTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if n > 1e12 {
+			return time.UnixMilli(n)
+		}
+		return time.Unix(n, 0)
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04:05.000"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+var (
+	htmlAnchorPattern    = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	htmlCodePattern      = regexp.MustCompile(`(?is)<code[^>]*>(.*?)</code>`)
+	htmlBreakPattern     = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlParagraphPattern = regexp.MustCompile(`(?i)</p>\s*`)
+	htmlTagPattern       = regexp.MustCompile(`<[^>]+>`)
+)
+
+// htmlToMarkdown 把 Disqus、Twikoo 保存的 HTML 评论转换为 Markdown 文本，保留段落、链接和行内代码
+func htmlToMarkdown(source string) string {
+	text := htmlAnchorPattern.ReplaceAllString(source, "[$2]($1)")
+	text = htmlCodePattern.ReplaceAllString(text, "`$1`")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlParagraphPattern.ReplaceAllString(text, "\n\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
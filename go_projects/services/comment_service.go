@@ -1,27 +1,98 @@
 package services
 
 import (
+	"blog-system/config"
 	"blog-system/models"
 	"blog-system/repositories"
+	"blog-system/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCommentStatus = errors.New("status must be one of pending, approved, rejected, spam")
+	ErrInvalidBulkAction    = errors.New("action must be one of approve, reject, spam, delete")
+	ErrCommentsClosed       = errors.New("comments are closed")
+	ErrInvalidCommentParent = errors.New("parent comment does not belong to this target")
+	ErrCommentEmpty         = errors.New("comment content is empty")
+	ErrCommentTooLong       = errors.New("comment is too long")
+	ErrCommentTooManyLinks  = errors.New("comment contains too many links")
+	ErrCommentEditToken     = errors.New("invalid edit token")
+	ErrCommentEditExpired   = errors.New("comment can no longer be edited")
+	ErrCommentFormTokenUsed = errors.New("form token has already been used, reload the form and try again")
+)
+
+// 批量审核操作
+const (
+	CommentActionApprove = "approve"
+	CommentActionReject  = "reject"
+	CommentActionSpam    = "spam"
+	CommentActionDelete  = "delete"
 )
 
+var bulkActionStatus = map[string]string{
+	CommentActionApprove: models.CommentStatusApproved,
+	CommentActionReject:  models.CommentStatusRejected,
+	CommentActionSpam:    models.CommentStatusSpam,
+}
+
 type CommentService interface {
-	GetComments(page, pageSize int) ([]models.Comment, int64, error)
+	GetComments(page, pageSize int) ([]PublicComment, int64, error)
+	GetAllComments(page, pageSize int) ([]models.Comment, int64, error)
 	GetPendingComments(page, pageSize int) ([]models.Comment, int64, error)
-	CreateComment(input *models.Comment) (*models.Comment, error)
+	// ResolveTarget 查找评论对象，key 为对象 ID（实验室也可以是 slug）
+	ResolveTarget(targetType, key string) (*CommentTarget, error)
+	GetCommentTree(target *CommentTarget, opts CommentTreeOptions) ([]*CommentNode, int64, error)
+	// CreateComment 返回创建的评论和只出现这一次的编辑令牌
+	CreateComment(input *models.Comment, meta CommentMeta) (*models.Comment, string, error)
+	EditOwnComment(id uint, token, content, ip string) (*models.Comment, error)
+	DeleteOwnComment(id uint, token string) error
+	GetCommentRevisions(id uint) ([]models.CommentRevision, error)
 	UpdateCommentStatus(id uint, status string) (*models.Comment, error)
+	BulkModerate(ids []uint, action string) (int64, error)
 	DeleteComment(id uint) error
 }
 
 type commentService struct {
-	repo repositories.CommentRepository
+	repo          repositories.CommentRepository
+	userRepo      repositories.UserRepository
+	targets       *commentTargetResolver
+	spam          *SpamPipeline
+	notifications NotificationService
 }
 
 func NewCommentService() CommentService {
-	return &commentService{repo: repositories.NewCommentRepository()}
+	repo := repositories.NewCommentRepository()
+	return &commentService{
+		repo:          repo,
+		userRepo:      repositories.NewUserRepository(),
+		targets:       newCommentTargetResolver(),
+		spam:          NewDefaultSpamPipeline(repo),
+		notifications: NewNotificationService(DefaultMailQueue()),
+	}
 }
 
-func (s *commentService) GetComments(page, pageSize int) ([]models.Comment, int64, error) {
+// GetComments 公开的最新评论列表，只包含已审核评论且隐藏邮箱和 IP
+func (s *commentService) GetComments(page, pageSize int) ([]PublicComment, int64, error) {
+	comments, total, err := s.repo.FindApproved(page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	return newPublicComments(comments), total, nil
+}
+
+// GetAllComments 管理端评论列表，包含所有状态和完整字段
+func (s *commentService) GetAllComments(page, pageSize int) ([]models.Comment, int64, error) {
 	return s.repo.FindAll(page, pageSize)
 }
 
@@ -33,20 +104,305 @@ func (s *commentService) GetPendingComments(page, pageSize int) ([]models.Commen
 	return s.repo.FindPending(page, pageSize)
 }
 
-func (s *commentService) CreateComment(input *models.Comment) (*models.Comment, error) {
-	err := s.repo.Create(input)
-	return input, err
+func (s *commentService) ResolveTarget(targetType, key string) (*CommentTarget, error) {
+	return s.targets.Resolve(targetType, key)
+}
+
+// GetCommentTree 返回评论对象下已审核评论组成的树，按顶层线程分页，total 为线程总数
+func (s *commentService) GetCommentTree(target *CommentTarget, opts CommentTreeOptions) ([]*CommentNode, int64, error) {
+	comments, err := s.repo.FindApprovedByTarget(target.Type, target.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	roots := buildCommentTree(comments)
+	sortCommentThreads(roots, opts.Sort)
+
+	total := int64(len(roots))
+	start := (opts.Page - 1) * opts.PageSize
+	if start >= len(roots) {
+		return []*CommentNode{}, total, nil
+	}
+	end := start + opts.PageSize
+	if end > len(roots) {
+		end = len(roots)
+	}
+	return roots[start:end], total, nil
+}
+
+// CreateComment 保存前运行反垃圾检查，按得分和评论对象的评论设置决定评论直接通过、待审核或拒绝
+func (s *commentService) CreateComment(input *models.Comment, meta CommentMeta) (*models.Comment, string, error) {
+	target, err := s.targets.ResolveComment(input)
+	if err != nil {
+		return nil, "", err
+	}
+	mode := target.Mode
+	if mode == models.CommentModeClosed {
+		return nil, "", ErrCommentsClosed
+	}
+	if err := s.checkCommenterEmail(input.Email); err != nil {
+		return nil, "", err
+	}
+	if input.ParentID != nil {
+		parent, err := s.repo.FindByID(*input.ParentID)
+		if err != nil || parent.TargetType != target.Type || parent.TargetID != target.ID {
+			return nil, "", ErrInvalidCommentParent
+		}
+	}
+
+	if err := renderCommentContent(input); err != nil {
+		return nil, "", err
+	}
+	token, err := newEditToken()
+	if err != nil {
+		return nil, "", err
+	}
+	input.EditTokenHash = hashEditToken(token)
+
+	input.Status = models.CommentStatusPending
+	if s.spam != nil {
+		meta.Permalink = siteBaseURL() + target.Path
+		verdict := s.spam.Evaluate(&SpamSubmission{Comment: input, CommentMeta: meta})
+		reasons, err := json.Marshal(verdict.Reasons)
+		if err != nil {
+			return nil, "", err
+		}
+		input.Status = verdict.Status
+		input.SpamScore = verdict.Score
+		input.SpamReasons = reasons
+	}
+
+	switch {
+	case mode == models.CommentModeModerated && input.Status == models.CommentStatusApproved:
+		input.Status = models.CommentStatusPending
+	case mode == models.CommentModeOpen && input.Status == models.CommentStatusPending && s.isTrusted(input):
+		input.Status = models.CommentStatusApproved
+	}
+
+	// 有效且属于该对象的表单令牌随评论一起记为已使用，同一令牌不能再发表第二条评论
+	var nonce *models.CommentFormNonce
+	if form, ok := parseCommentFormToken(meta.FormToken); ok && form.boundTo(input) {
+		nonce = &models.CommentFormNonce{Nonce: form.Nonce, ExpiresAt: form.IssuedAt.Add(CommentFormTokenTTL)}
+	}
+	if err := s.repo.CreateWithFormNonce(input, nonce); err != nil {
+		if errors.Is(err, repositories.ErrFormNonceUsed) {
+			return nil, "", ErrCommentFormTokenUsed
+		}
+		return input, "", err
+	}
+
+	switch input.Status {
+	case models.CommentStatusPending:
+		s.notifications.NotifyCommentPending(input)
+	case models.CommentStatusApproved:
+		s.notifications.NotifyReplyApproved(input)
+	}
+	return input, token, nil
+}
+
+// checkCommenterEmail 配置要求评论前验证邮箱时，评论邮箱属于尚未验证邮箱的账号则拒绝；
+// 令牌只能识别登录状态，未验证的用户不带令牌以访客身份提交也会在这里被拦下
+func (s *commentService) checkCommenterEmail(email string) error {
+	if strings.TrimSpace(email) == "" || !EmailVerificationRequired(VerifiedEmailForComment) {
+		return nil
+	}
+	user, err := s.userRepo.FindByEmail(strings.TrimSpace(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// EditOwnComment 评论者凭编辑令牌修改评论，旧版本存入修改历史，修改后重新进入待审核
+func (s *commentService) EditOwnComment(id uint, token, content, ip string) (*models.Comment, error) {
+	comment, err := s.authorizeOwner(id, token)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &models.CommentRevision{
+		CommentID:   comment.ID,
+		Content:     comment.Content,
+		ContentHTML: comment.ContentHTML,
+		Status:      comment.Status,
+		IP:          comment.IP,
+	}
+	comment.Content = content
+	if err := renderCommentContent(comment); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	comment.Status = models.CommentStatusPending
+	comment.IP = ip
+	comment.EditedAt = &now
+	if err := s.repo.UpdateWithRevision(comment, revision); err != nil {
+		return nil, err
+	}
+
+	// 待审核的评论已经通知过管理员，只有已通过的评论被修改时才需要再次通知
+	if revision.Status == models.CommentStatusApproved {
+		s.notifications.NotifyCommentPending(comment)
+	}
+	return comment, nil
+}
+
+// DeleteOwnComment 评论者凭编辑令牌撤回评论
+func (s *commentService) DeleteOwnComment(id uint, token string) error {
+	comment, err := s.authorizeOwner(id, token)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(comment)
+}
+
+func (s *commentService) GetCommentRevisions(id uint) ([]models.CommentRevision, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.FindRevisions(id)
+}
+
+// authorizeOwner 校验编辑令牌，并确认评论仍在可编辑时间内且未被拒绝
+func (s *commentService) authorizeOwner(id uint, token string) (*models.Comment, error) {
+	comment, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.EditTokenHash == "" || token == "" ||
+		subtle.ConstantTimeCompare([]byte(hashEditToken(token)), []byte(comment.EditTokenHash)) != 1 {
+		return nil, ErrCommentEditToken
+	}
+	switch comment.Status {
+	case models.CommentStatusRejected, models.CommentStatusSpam:
+		return nil, ErrCommentEditExpired
+	}
+	if time.Now().After(CommentEditDeadline(comment)) {
+		return nil, ErrCommentEditExpired
+	}
+	return comment, nil
+}
+
+// CommentEditDeadline 评论者可以修改或撤回评论的截止时间
+func CommentEditDeadline(comment *models.Comment) time.Time {
+	return comment.CreatedAt.Add(time.Duration(config.AppConfig.CommentEditWindowMinutes) * time.Minute)
+}
+
+func newEditToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashEditToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// renderCommentContent 检查长度和链接数限制，并把 Markdown 原文渲染为净化后的 HTML
+func renderCommentContent(comment *models.Comment) error {
+	comment.Content = strings.TrimSpace(comment.Content)
+	if comment.Content == "" {
+		return ErrCommentEmpty
+	}
+	if max := config.AppConfig.CommentMaxLength; utf8.RuneCountInString(comment.Content) > max {
+		return fmt.Errorf("%w (max %d characters)", ErrCommentTooLong, max)
+	}
+
+	html, links, err := utils.RenderCommentMarkdown(comment.Content)
+	if err != nil {
+		return err
+	}
+	if max := config.AppConfig.CommentMaxLinks; links > max {
+		return fmt.Errorf("%w (max %d)", ErrCommentTooManyLinks, max)
+	}
+	comment.ContentHTML = html
+	return nil
+}
+
+// isTrusted 同一邮箱已有通过审核的评论时视为可信评论者
+func (s *commentService) isTrusted(comment *models.Comment) bool {
+	if !config.AppConfig.CommentAutoApproveTrusted || comment.Email == "" {
+		return false
+	}
+	count, err := s.repo.CountApprovedByEmailHash(utils.EmailHash(comment.Email))
+	return err == nil && count > 0
+}
+
+// EffectiveCommentMode 文章当前生效的评论模式，超过自动关闭天数的文章视为已关闭
+func EffectiveCommentMode(article *models.Article) string {
+	mode := article.CommentMode
+	if !models.ValidCommentMode(mode) {
+		mode = models.CommentModeOpen
+	}
+
+	days := article.CommentAutoCloseDays
+	if days == 0 {
+		days = config.AppConfig.CommentAutoCloseDays
+	}
+	if days > 0 && article.PublishedAt != nil && time.Since(*article.PublishedAt) > time.Duration(days)*24*time.Hour {
+		return models.CommentModeClosed
+	}
+	return mode
 }
 
 func (s *commentService) UpdateCommentStatus(id uint, status string) (*models.Comment, error) {
+	if !models.ValidCommentStatus(status) {
+		return nil, ErrInvalidCommentStatus
+	}
 	comment, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
 
+	previous := comment.Status
 	comment.Status = status
-	err = s.repo.Update(comment)
-	return comment, err
+	if err := s.repo.Update(comment); err != nil {
+		return comment, err
+	}
+
+	if previous != models.CommentStatusApproved && status == models.CommentStatusApproved {
+		s.notifications.NotifyReplyApproved(comment)
+	}
+	return comment, nil
+}
+
+// BulkModerate 对一组评论执行批量操作，返回受影响的评论数
+func (s *commentService) BulkModerate(ids []uint, action string) (int64, error) {
+	if action == CommentActionDelete {
+		return s.repo.DeleteByIDs(ids)
+	}
+	status, ok := bulkActionStatus[action]
+	if !ok {
+		return 0, ErrInvalidBulkAction
+	}
+
+	comments, err := s.repo.FindByIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := s.repo.UpdateStatusByIDs(ids, status)
+	if err != nil {
+		return 0, err
+	}
+
+	if status == models.CommentStatusApproved {
+		for i := range comments {
+			if comments[i].Status != models.CommentStatusApproved {
+				comments[i].Status = status
+				s.notifications.NotifyReplyApproved(&comments[i])
+			}
+		}
+	}
+	return affected, nil
 }
 
 func (s *commentService) DeleteComment(id uint) error {
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/repositories"
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCommentTarget = errors.New("target_type must be one of article, lab, music, playlist, guestbook")
+
+// CommentTarget 评论所属对象的摘要，供校验、通知邮件和反垃圾使用
+type CommentTarget struct {
+	Type  string `json:"type"`
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	Path  string `json:"path"` // 站内页面路径，如 /articles/1
+	Mode  string `json:"mode"` // 当前生效的评论模式
+}
+
+// commentTargetResolver 按 target_type 查找评论对象，不存在或未公开时返回 gorm.ErrRecordNotFound
+type commentTargetResolver struct {
+	articleRepo repositories.ArticleRepository
+	labRepo     repositories.LabRepository
+	musicRepo   repositories.MusicRepository
+}
+
+func newCommentTargetResolver() *commentTargetResolver {
+	return &commentTargetResolver{
+		articleRepo: repositories.NewArticleRepository(),
+		labRepo:     repositories.NewLabRepository(),
+		musicRepo:   repositories.NewMusicRepository(),
+	}
+}
+
+// Resolve key 为对象 ID；实验室也可以使用 slug，留言板忽略 key
+func (r *commentTargetResolver) Resolve(targetType, key string) (*CommentTarget, error) {
+	if targetType == models.CommentTargetGuestbook {
+		mode := config.AppConfig.CommentGuestbookMode
+		if !models.ValidCommentMode(mode) {
+			mode = models.CommentModeOpen
+		}
+		return &CommentTarget{Type: targetType, Title: "留言板", Path: "/guestbook", Mode: mode}, nil
+	}
+	if !models.ValidCommentTarget(targetType) {
+		return nil, ErrInvalidCommentTarget
+	}
+
+	if targetType == models.CommentTargetLab {
+		return r.resolveLab(key)
+	}
+
+	id, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	key = strconv.FormatUint(id, 10)
+
+	switch targetType {
+	case models.CommentTargetArticle:
+		article, err := r.articleRepo.FindByID(key)
+		if err != nil {
+			return nil, err
+		}
+		if article.Status != "published" {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return &CommentTarget{Type: targetType, ID: article.ID, Title: article.Title, Path: "/articles/" + key, Mode: EffectiveCommentMode(article)}, nil
+	case models.CommentTargetMusic:
+		music, err := r.musicRepo.FindByID(uint(id))
+		if err != nil {
+			return nil, err
+		}
+		if !music.IsPublic {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return &CommentTarget{Type: targetType, ID: music.ID, Title: music.Title, Path: "/music/" + key, Mode: models.CommentModeOpen}, nil
+	default:
+		playlist, err := r.musicRepo.FindPlaylistByID(uint(id))
+		if err != nil {
+			return nil, err
+		}
+		if !playlist.IsPublic {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return &CommentTarget{Type: targetType, ID: playlist.ID, Title: playlist.Name, Path: "/music/playlists/" + key, Mode: models.CommentModeOpen}, nil
+	}
+}
+
+// resolveLab 先按 slug 查找，找不到且 key 为数字时再按 ID 查找，避免数字 slug 被当作其他模块的 ID
+func (r *commentTargetResolver) resolveLab(key string) (*CommentTarget, error) {
+	lab, err := r.labRepo.FindBySlug(key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if id, parseErr := strconv.ParseUint(key, 10, 64); parseErr == nil {
+			lab, err = r.labRepo.FindByID(uint(id))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return labCommentTarget(lab), nil
+}
+
+func labCommentTarget(lab *models.Lab) *CommentTarget {
+	return &CommentTarget{Type: models.CommentTargetLab, ID: lab.ID, Title: lab.Title, Path: "/labs/" + lab.Slug, Mode: models.CommentModeOpen}
+}
+
+// ResolveComment 查找评论所属对象，用于通知等已有评论的场景
+func (r *commentTargetResolver) ResolveComment(comment *models.Comment) (*CommentTarget, error) {
+	if comment.TargetType == models.CommentTargetLab {
+		lab, err := r.labRepo.FindByID(comment.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		return labCommentTarget(lab), nil
+	}
+	return r.Resolve(comment.TargetType, strconv.FormatUint(uint64(comment.TargetID), 10))
+}
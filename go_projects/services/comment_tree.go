@@ -0,0 +1,81 @@
+package services
+
+import (
+	"blog-system/models"
+	"sort"
+)
+
+// 评论线程排序方式
+const (
+	CommentSortOldest  = "oldest"
+	CommentSortNewest  = "newest"
+	CommentSortReplies = "replies"
+)
+
+// CommentTreeOptions 评论树分页参数，分页以顶层评论（线程）为单位
+type CommentTreeOptions struct {
+	Page     int
+	PageSize int
+	Sort     string
+}
+
+// CommentNode 评论树节点，ReplyCount 为该评论下所有层级回复的总数
+type CommentNode struct {
+	PublicComment
+	ReplyCount int            `json:"reply_count"`
+	Replies    []*CommentNode `json:"replies"`
+}
+
+// buildCommentTree 将按时间升序排列的扁平评论组装成树；父评论不在列表中（未审核或已删除）的回复一并隐藏
+func buildCommentTree(comments []models.Comment) []*CommentNode {
+	nodes := make(map[uint]*CommentNode, len(comments))
+	for _, comment := range comments {
+		nodes[comment.ID] = &CommentNode{
+			PublicComment: NewPublicComment(comment),
+			Replies:       []*CommentNode{},
+		}
+	}
+
+	roots := []*CommentNode{}
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		if comment.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	for _, root := range roots {
+		countReplies(root)
+	}
+	return roots
+}
+
+func countReplies(node *CommentNode) int {
+	total := 0
+	for _, reply := range node.Replies {
+		total += 1 + countReplies(reply)
+	}
+	node.ReplyCount = total
+	return total
+}
+
+// sortCommentThreads 只对顶层线程排序，线程内的回复始终按时间先后排列
+func sortCommentThreads(roots []*CommentNode, order string) {
+	switch order {
+	case CommentSortOldest:
+		// 查询结果已按时间升序
+	case CommentSortReplies:
+		sort.SliceStable(roots, func(i, j int) bool {
+			if roots[i].ReplyCount != roots[j].ReplyCount {
+				return roots[i].ReplyCount > roots[j].ReplyCount
+			}
+			return roots[i].CreatedAt.After(roots[j].CreatedAt)
+		})
+	default:
+		sort.SliceStable(roots, func(i, j int) bool { return roots[i].CreatedAt.After(roots[j].CreatedAt) })
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"blog-system/config"
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates/email/*.tmpl
+var emailTemplateFS embed.FS
+
+//go:embed templates/page/*.tmpl
+var pageTemplateFS embed.FS
+
+// 退订页面的状态
+const (
+	UnsubscribePageConfirm = "confirm"
+	UnsubscribePageDone    = "done"
+	UnsubscribePageInvalid = "invalid"
+)
+
+// emailBase 所有邮件模板共用的字段
+type emailBase struct {
+	Subject        string
+	SiteName       string
+	SiteURL        string
+	UnsubscribeURL string
+}
+
+func newEmailBase(subject, unsubscribeURL string) emailBase {
+	return emailBase{
+		Subject:        subject,
+		SiteName:       config.AppConfig.SiteName,
+		SiteURL:        siteBaseURL(),
+		UnsubscribeURL: unsubscribeURL,
+	}
+}
+
+// siteBaseURL 站点对外地址，未配置 site.url 时使用本机地址
+func siteBaseURL() string {
+	if config.AppConfig.SiteURL != "" {
+		return config.AppConfig.SiteURL
+	}
+	return "http://localhost:" + config.AppConfig.ServerPort
+}
+
+// renderEmail 渲染 templates/email 下同名的 .html.tmpl（套用 layout）与 .txt.tmpl
+func renderEmail(name string, data interface{}) (string, string, error) {
+	htmlTmpl, err := htmltemplate.ParseFS(emailTemplateFS, "templates/email/layout.html.tmpl", "templates/email/"+name+".html.tmpl")
+	if err != nil {
+		return "", "", err
+	}
+	textTmpl, err := texttemplate.ParseFS(emailTemplateFS, "templates/email/"+name+".txt.tmpl")
+	if err != nil {
+		return "", "", err
+	}
+
+	var html, text bytes.Buffer
+	if err := htmlTmpl.ExecuteTemplate(&html, "layout", data); err != nil {
+		return "", "", err
+	}
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return "", "", err
+	}
+	return html.String(), text.String(), nil
+}
+
+// RenderUnsubscribePage 渲染邮件退订链接打开的页面，state 为 UnsubscribePage* 之一
+func RenderUnsubscribePage(state string) (string, error) {
+	tmpl, err := htmltemplate.ParseFS(pageTemplateFS, "templates/page/unsubscribe.html.tmpl")
+	if err != nil {
+		return "", err
+	}
+	var html bytes.Buffer
+	err = tmpl.Execute(&html, struct {
+		State    string
+		SiteName string
+		SiteURL  string
+	}{state, config.AppConfig.SiteName, siteBaseURL()})
+	return html.String(), err
+}
@@ -3,7 +3,6 @@ package services
 import (
 	"blog-system/models"
 	"blog-system/repositories"
-	"github.com/gosimple/slug"
 )
 
 type LabService interface {
@@ -16,11 +15,12 @@ type LabService interface {
 }
 
 type labService struct {
-	repo repositories.LabRepository
+	repo        repositories.LabRepository
+	slugService SlugService
 }
 
 func NewLabService() LabService {
-	return &labService{repo: repositories.NewLabRepository()}
+	return &labService{repo: repositories.NewLabRepository(), slugService: NewSlugService()}
 }
 
 func (s *labService) GetLabs() ([]models.Lab, error) {
@@ -28,16 +28,25 @@ func (s *labService) GetLabs() ([]models.Lab, error) {
 }
 
 func (s *labService) GetLab(id uint) (*models.Lab, error) {
-	return s.repo.FindByID(id)
+	lab, err := s.repo.FindByID(id)
+	if err != nil {
+		return lab, err
+	}
+	applyLabSEO(lab)
+	return lab, nil
 }
 
 func (s *labService) GetLabBySlug(slug string) (*models.Lab, error) {
-	return s.repo.FindBySlug(slug)
+	lab, err := s.repo.FindBySlug(slug)
+	if err != nil {
+		return lab, err
+	}
+	applyLabSEO(lab)
+	return lab, nil
 }
 
 func (s *labService) CreateLab(input *models.Lab) (*models.Lab, error) {
-	input.Slug = slug.Make(input.Title)
-	err := s.repo.Create(input)
+	err := s.repo.CreateWithSlug(input, s.slugService.Assign(input.Title, input.Slug, 0))
 	return input, err
 }
 
@@ -47,8 +56,14 @@ func (s *labService) UpdateLab(id uint, input *models.Lab) (*models.Lab, error)
 		return nil, err
 	}
 
+	var slugFn repositories.SlugFunc
+	if input.Slug != "" && input.Slug != lab.Slug {
+		slugFn = s.slugService.Assign("", input.Slug, lab.ID)
+	} else if input.Slug == "" && input.Title != lab.Title {
+		slugFn = s.slugService.Assign(input.Title, "", lab.ID)
+	}
+
 	lab.Title = input.Title
-	lab.Slug = slug.Make(input.Title)
 	lab.Subtitle = input.Subtitle
 	lab.Badge = input.Badge
 	lab.BadgeColor = input.BadgeColor
@@ -58,8 +73,13 @@ func (s *labService) UpdateLab(id uint, input *models.Lab) (*models.Lab, error)
 	lab.Content = input.Content
 	lab.Highlights = input.Highlights
 	lab.ResourceLinks = input.ResourceLinks
+	lab.SEO = input.SEO
 
-	err = s.repo.Update(lab)
+	if slugFn != nil {
+		err = s.repo.UpdateWithSlug(lab, slugFn)
+	} else {
+		err = s.repo.Update(lab)
+	}
 	return lab, err
 }
 
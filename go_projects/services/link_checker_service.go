@@ -0,0 +1,376 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/repositories"
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 链接类型
+const (
+	LinkKindArticle  = "article"
+	LinkKindLab      = "lab"
+	LinkKindUpload   = "upload"
+	LinkKindMusic    = "music"
+	LinkKindExternal = "external"
+	LinkKindInvalid  = "invalid"
+)
+
+// maxLinkCheckConcurrency 外部链接探测并发数上限
+const maxLinkCheckConcurrency = 16
+
+// LinkCheckOptions 链接检查选项
+type LinkCheckOptions struct {
+	CheckExternal bool
+	Concurrency   int
+	Timeout       time.Duration
+}
+
+// LinkIssue 单个失效链接
+type LinkIssue struct {
+	URL        string `json:"url"`
+	Kind       string `json:"kind"`
+	Reason     string `json:"reason"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+// LinkReport 单篇内容的检查结果
+type LinkReport struct {
+	SourceType string      `json:"source_type"` // article, lab
+	SourceID   uint        `json:"source_id"`
+	Slug       string      `json:"slug"`
+	Title      string      `json:"title"`
+	Checked    int         `json:"checked"`
+	Broken     []LinkIssue `json:"broken"`
+}
+
+type LinkCheckerService interface {
+	CheckAll(opts LinkCheckOptions) ([]LinkReport, error)
+	CheckArticle(id string, opts LinkCheckOptions) (*LinkReport, error)
+}
+
+type linkCheckerService struct {
+	articleRepo repositories.ArticleRepository
+	labRepo     repositories.LabRepository
+	client      *http.Client
+}
+
+func NewLinkCheckerService() LinkCheckerService {
+	return NewLinkCheckerServiceWithClient(&http.Client{})
+}
+
+// NewLinkCheckerServiceWithClient 使用自定义 HTTP 客户端，便于指向本地服务调试
+func NewLinkCheckerServiceWithClient(client *http.Client) LinkCheckerService {
+	return &linkCheckerService{
+		articleRepo: repositories.NewArticleRepository(),
+		labRepo:     repositories.NewLabRepository(),
+		client:      client,
+	}
+}
+
+var (
+	markdownLinkPattern = regexp.MustCompile(`!?\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	markdownRefPattern  = regexp.MustCompile(`(?m)^\s{0,3}\[[^\]]+\]:\s*<?(\S+?)>?(?:\s+.*)?$`)
+	htmlLinkPattern     = regexp.MustCompile(`(?i)<(?:a|img|source|audio|video)\b[^>]*?\s(?:href|src)\s*=\s*["']([^"']+)["']`)
+	autoLinkPattern     = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	fencedCodePattern   = regexp.MustCompile("(?s)```.*?```|~~~.*?~~~")
+	inlineCodePattern   = regexp.MustCompile("`[^`\n]*`")
+)
+
+// ExtractLinks 从 Markdown 文本中提取链接，忽略代码块中的内容
+func ExtractLinks(markdown string) []string {
+	text := fencedCodePattern.ReplaceAllString(markdown, "")
+	text = inlineCodePattern.ReplaceAllString(text, "")
+
+	seen := make(map[string]bool)
+	var links []string
+	for _, pattern := range []*regexp.Regexp{markdownLinkPattern, markdownRefPattern, htmlLinkPattern, autoLinkPattern} {
+		for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+			link := strings.TrimSpace(match[1])
+			if link == "" || seen[link] {
+				continue
+			}
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func (s *linkCheckerService) CheckAll(opts LinkCheckOptions) ([]LinkReport, error) {
+	articles, err := s.articleRepo.FindAllWithContent()
+	if err != nil {
+		return nil, err
+	}
+	labs, err := s.labRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	index := newLinkIndex(articles, labs)
+	prober := s.newProber(opts)
+
+	reports := make([]LinkReport, 0, len(articles)+len(labs))
+	for _, article := range articles {
+		links := ExtractLinks(article.Content)
+		if article.CoverImage != "" {
+			links = append(links, article.CoverImage)
+		}
+		reports = append(reports, s.check(LinkKindArticle, article.ID, article.Slug, article.Title, links, index, prober))
+	}
+	for _, lab := range labs {
+		links := ExtractLinks(lab.Content)
+		if lab.HeroImage != "" {
+			links = append(links, lab.HeroImage)
+		}
+		reports = append(reports, s.check(LinkKindLab, lab.ID, lab.Slug, lab.Title, links, index, prober))
+	}
+	return reports, nil
+}
+
+func (s *linkCheckerService) CheckArticle(id string, opts LinkCheckOptions) (*LinkReport, error) {
+	article, err := s.articleRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	articles, err := s.articleRepo.FindAllWithContent()
+	if err != nil {
+		return nil, err
+	}
+	labs, err := s.labRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	links := ExtractLinks(article.Content)
+	if article.CoverImage != "" {
+		links = append(links, article.CoverImage)
+	}
+	report := s.check(LinkKindArticle, article.ID, article.Slug, article.Title, links, newLinkIndex(articles, labs), s.newProber(opts))
+	return &report, nil
+}
+
+func (s *linkCheckerService) check(sourceType string, id uint, slug, title string, links []string, index *linkIndex, prober *externalProber) LinkReport {
+	report := LinkReport{
+		SourceType: sourceType,
+		SourceID:   id,
+		Slug:       slug,
+		Title:      title,
+		Broken:     []LinkIssue{},
+	}
+
+	var external []string
+	for _, link := range links {
+		if isExternalLink(link) {
+			if prober != nil {
+				external = append(external, link)
+				report.Checked++
+			}
+			continue
+		}
+		kind, reason, ok := index.resolve(link)
+		if kind == "" {
+			continue
+		}
+		report.Checked++
+		if !ok {
+			report.Broken = append(report.Broken, LinkIssue{URL: link, Kind: kind, Reason: reason})
+		}
+	}
+
+	if prober != nil {
+		report.Broken = append(report.Broken, prober.probeAll(external)...)
+	}
+	return report
+}
+
+func isExternalLink(link string) bool {
+	lower := strings.ToLower(link)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "//")
+}
+
+// linkIndex 已存在的站内资源索引
+type linkIndex struct {
+	articleSlugs map[string]bool
+	articleIDs   map[string]bool
+	labSlugs     map[string]bool
+}
+
+func newLinkIndex(articles []models.Article, labs []models.Lab) *linkIndex {
+	index := &linkIndex{
+		articleSlugs: make(map[string]bool, len(articles)),
+		articleIDs:   make(map[string]bool, len(articles)),
+		labSlugs:     make(map[string]bool, len(labs)),
+	}
+	for _, article := range articles {
+		index.articleSlugs[article.Slug] = true
+		index.articleIDs[strconv.FormatUint(uint64(article.ID), 10)] = true
+	}
+	for _, lab := range labs {
+		index.labSlugs[lab.Slug] = true
+	}
+	return index
+}
+
+// resolve 解析站内链接，kind 为空表示不是需要检查的站内链接（锚点、mailto 等）
+func (idx *linkIndex) resolve(link string) (kind, reason string, ok bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return LinkKindInvalid, "invalid url", false
+	}
+	if u.Scheme != "" || !strings.HasPrefix(u.Path, "/") {
+		return "", "", true
+	}
+
+	path, err := url.PathUnescape(u.Path)
+	if err != nil {
+		path = u.Path
+	}
+
+	switch {
+	case strings.HasPrefix(path, "/uploads/"):
+		return LinkKindUpload, "file not found", fileExists(config.AppConfig.UploadPath, strings.TrimPrefix(path, "/uploads/"))
+	case strings.HasPrefix(path, "/music/"):
+		return LinkKindMusic, "file not found", fileExists(config.AppConfig.MusicPath, strings.TrimPrefix(path, "/music/"))
+	case strings.HasPrefix(path, "/articles/"):
+		key := strings.Trim(strings.TrimPrefix(path, "/articles/"), "/")
+		return LinkKindArticle, "article not found", idx.articleSlugs[key] || idx.articleIDs[key]
+	case strings.HasPrefix(path, "/labs/"):
+		key := strings.Trim(strings.TrimPrefix(path, "/labs/"), "/")
+		return LinkKindLab, "lab not found", idx.labSlugs[key]
+	}
+	return "", "", true
+}
+
+// fileExists 检查 base 目录下的文件是否存在，拒绝跳出 base 的路径
+func fileExists(base, name string) bool {
+	cleaned := filepath.Clean("/" + name)
+	if cleaned == "/" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(base, cleaned))
+	return err == nil && !info.IsDir()
+}
+
+// externalProber 并发受限的外部链接探测器，同一次检查内缓存结果
+type externalProber struct {
+	client  *http.Client
+	timeout time.Duration
+	sem     chan struct{}
+
+	mu    sync.Mutex
+	cache map[string]*LinkIssue
+}
+
+func (s *linkCheckerService) newProber(opts LinkCheckOptions) *externalProber {
+	if !opts.CheckExternal {
+		return nil
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = config.AppConfig.LinkCheckConcurrency
+	}
+	// 并发数可以由接口参数指定，限制上限避免一次发出大量外部请求
+	if concurrency <= 0 {
+		concurrency = 1
+	} else if concurrency > maxLinkCheckConcurrency {
+		concurrency = maxLinkCheckConcurrency
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = time.Duration(config.AppConfig.LinkCheckTimeout) * time.Second
+	}
+	return &externalProber{
+		client:  s.client,
+		timeout: timeout,
+		sem:     make(chan struct{}, concurrency),
+		cache:   make(map[string]*LinkIssue),
+	}
+}
+
+func (p *externalProber) probeAll(links []string) []LinkIssue {
+	results := make([]*LinkIssue, len(links))
+	var wg sync.WaitGroup
+	for i, link := range links {
+		wg.Add(1)
+		go func(i int, link string) {
+			defer wg.Done()
+			results[i] = p.probe(link)
+		}(i, link)
+	}
+	wg.Wait()
+
+	issues := []LinkIssue{}
+	for _, issue := range results {
+		if issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+	return issues
+}
+
+func (p *externalProber) probe(link string) *LinkIssue {
+	p.mu.Lock()
+	if issue, ok := p.cache[link]; ok {
+		p.mu.Unlock()
+		return issue
+	}
+	p.mu.Unlock()
+
+	p.sem <- struct{}{}
+	issue := p.request(link)
+	<-p.sem
+
+	p.mu.Lock()
+	p.cache[link] = issue
+	p.mu.Unlock()
+	return issue
+}
+
+func (p *externalProber) request(link string) *LinkIssue {
+	target := link
+	if strings.HasPrefix(target, "//") {
+		target = "https:" + target
+	}
+
+	status, err := p.do(http.MethodHead, target)
+	// 部分站点不支持 HEAD，回退到 GET
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented || status == http.StatusForbidden) {
+		status, err = p.do(http.MethodGet, target)
+	}
+	if err != nil {
+		return &LinkIssue{URL: link, Kind: LinkKindExternal, Reason: err.Error()}
+	}
+	if status >= 400 {
+		return &LinkIssue{URL: link, Kind: LinkKindExternal, Reason: http.StatusText(status), StatusCode: status}
+	}
+	return nil
+}
+
+func (p *externalProber) do(method, target string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "blog-system-link-checker/1.0")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+	}{
+		{
+			name:     "inline links and images",
+			markdown: "see [post](/articles/hello) and ![cover](/uploads/a.png \"title\")",
+			want:     []string{"/articles/hello", "/uploads/a.png"},
+		},
+		{
+			name:     "reference definitions and html",
+			markdown: "[ref]: https://example.com/doc\n\n<img src=\"/uploads/b.png\"> <a href='/labs/demo'>lab</a>",
+			want:     []string{"https://example.com/doc", "/uploads/b.png", "/labs/demo"},
+		},
+		{
+			name:     "autolink",
+			markdown: "visit <https://example.com/>",
+			want:     []string{"https://example.com/"},
+		},
+		{
+			name:     "code is ignored",
+			markdown: "```\n[x](/articles/in-fence)\n```\n`[y](/articles/inline)` [z](/articles/real)",
+			want:     []string{"/articles/real"},
+		},
+		{
+			name:     "duplicates are reported once",
+			markdown: "[a](/articles/x) [b](/articles/x)",
+			want:     []string{"/articles/x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractLinks(tt.markdown); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractLinks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkIndexResolve(t *testing.T) {
+	uploads := t.TempDir()
+	if err := os.WriteFile(filepath.Join(uploads, "exists.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config.AppConfig = &config.Config{UploadPath: uploads, MusicPath: t.TempDir()}
+
+	index := newLinkIndex(
+		[]models.Article{{ID: 7, Slug: "hello-world"}},
+		[]models.Lab{{Slug: "demo"}},
+	)
+
+	tests := []struct {
+		link     string
+		wantKind string
+		wantOK   bool
+	}{
+		{"/articles/hello-world", LinkKindArticle, true},
+		{"/articles/7", LinkKindArticle, true},
+		{"/articles/missing", LinkKindArticle, false},
+		{"/labs/demo/", LinkKindLab, true},
+		{"/labs/other", LinkKindLab, false},
+		{"/uploads/exists.png", LinkKindUpload, true},
+		{"/uploads/missing.png", LinkKindUpload, false},
+		{"/uploads/../../etc/passwd", LinkKindUpload, false},
+		{"/music/none.mp3", LinkKindMusic, false},
+		{"#section", "", true},
+		{"mailto:someone@example.com", "", true},
+		{"/about", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			kind, _, ok := index.resolve(tt.link)
+			if kind != tt.wantKind || ok != tt.wantOK {
+				t.Errorf("resolve(%q) = (%q, %v), want (%q, %v)", tt.link, kind, ok, tt.wantKind, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestExternalProber(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string][]string{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], r.Method)
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path        string
+		wantBroken  bool
+		wantStatus  int
+		wantMethods []string
+	}{
+		{path: "/ok", wantMethods: []string{http.MethodHead}},
+		{path: "/missing", wantBroken: true, wantStatus: http.StatusNotFound, wantMethods: []string{http.MethodHead}},
+		{path: "/error", wantBroken: true, wantStatus: http.StatusInternalServerError, wantMethods: []string{http.MethodHead}},
+		{path: "/no-head", wantMethods: []string{http.MethodHead, http.MethodGet}},
+		{path: "/slow", wantBroken: true},
+	}
+
+	service := &linkCheckerService{client: server.Client()}
+	prober := service.newProber(LinkCheckOptions{CheckExternal: true, Concurrency: 2, Timeout: 100 * time.Millisecond})
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			issue := prober.probe(server.URL + tt.path)
+			if (issue != nil) != tt.wantBroken {
+				t.Fatalf("probe() = %+v, want broken %v", issue, tt.wantBroken)
+			}
+			if issue != nil && issue.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", issue.StatusCode, tt.wantStatus)
+			}
+			mu.Lock()
+			methods := requests[tt.path]
+			mu.Unlock()
+			if tt.wantMethods != nil && !reflect.DeepEqual(methods, tt.wantMethods) {
+				t.Errorf("methods = %v, want %v", methods, tt.wantMethods)
+			}
+		})
+	}
+
+	t.Run("results are cached per check", func(t *testing.T) {
+		prober.probe(server.URL + "/ok")
+		mu.Lock()
+		defer mu.Unlock()
+		if got := len(requests["/ok"]); got != 1 {
+			t.Errorf("/ok requested %d times, want 1", got)
+		}
+	})
+}
+
+func TestExternalProberConcurrencyLimit(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		concurrency int
+		want        int32
+	}{
+		{concurrency: 1, want: 1},
+		{concurrency: 3, want: 3},
+		{concurrency: 100, want: maxLinkCheckConcurrency},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&peak, 0)
+		service := &linkCheckerService{client: server.Client()}
+		prober := service.newProber(LinkCheckOptions{CheckExternal: true, Concurrency: tt.concurrency, Timeout: time.Second})
+
+		links := make([]string, 2*maxLinkCheckConcurrency)
+		for i := range links {
+			links[i] = server.URL + "/page/" + strconv.Itoa(i)
+		}
+		if issues := prober.probeAll(links); len(issues) != 0 {
+			t.Fatalf("concurrency %d: unexpected issues %+v", tt.concurrency, issues)
+		}
+		if got := atomic.LoadInt32(&peak); got > tt.want {
+			t.Errorf("concurrency %d: peak %d requests in flight, want at most %d", tt.concurrency, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"blog-system/config"
+	"log"
+	"sync"
+	"time"
+)
+
+// mailRetryBase 第 n 次重试前等待 mailRetryBase * 2^(n-1)
+const mailRetryBase = 5 * time.Second
+
+type mailJob struct {
+	msg     *MailMessage
+	attempt int
+}
+
+// MailQueue 异步发送邮件，失败后按指数退避重试，调用方不会被 SMTP 阻塞
+type MailQueue struct {
+	mailer     Mailer
+	jobs       chan mailJob
+	maxRetries int
+	retryBase  time.Duration
+	wg         sync.WaitGroup
+}
+
+func NewMailQueue(mailer Mailer, size, maxRetries int) *MailQueue {
+	q := &MailQueue{
+		mailer:     mailer,
+		jobs:       make(chan mailJob, size),
+		maxRetries: maxRetries,
+		retryBase:  mailRetryBase,
+	}
+	go q.run()
+	return q
+}
+
+var (
+	defaultMailQueue     *MailQueue
+	defaultMailQueueOnce sync.Once
+)
+
+// DefaultMailQueue 按配置创建的全局发送队列；未启用邮件时返回 nil
+func DefaultMailQueue() *MailQueue {
+	defaultMailQueueOnce.Do(func() {
+		cfg := config.AppConfig
+		if !cfg.MailEnabled {
+			return
+		}
+		defaultMailQueue = NewMailQueue(NewSMTPMailer(smtpOptionsFromConfig()), cfg.MailQueueSize, cfg.MailMaxRetries)
+	})
+	return defaultMailQueue
+}
+
+// Enqueue 加入发送队列，队列已满时丢弃并返回 false
+func (q *MailQueue) Enqueue(msg *MailMessage) bool {
+	return q.push(mailJob{msg: msg})
+}
+
+func (q *MailQueue) push(job mailJob) bool {
+	q.wg.Add(1)
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		q.wg.Done()
+		log.Printf("邮件队列已满，丢弃邮件: %s -> %v", job.msg.Subject, job.msg.To)
+		return false
+	}
+}
+
+// Wait 等待队列中（包括等待重试）的邮件全部处理完毕
+func (q *MailQueue) Wait() {
+	q.wg.Wait()
+}
+
+func (q *MailQueue) run() {
+	for job := range q.jobs {
+		q.process(job)
+	}
+}
+
+func (q *MailQueue) process(job mailJob) {
+	defer q.wg.Done()
+
+	err := q.mailer.Send(job.msg)
+	if err == nil {
+		return
+	}
+	if job.attempt >= q.maxRetries {
+		log.Printf("邮件发送失败，已重试 %d 次: %s -> %v: %v", job.attempt, job.msg.Subject, job.msg.To, err)
+		return
+	}
+
+	job.attempt++
+	delay := q.retryBase << (job.attempt - 1)
+	log.Printf("邮件发送失败，%s 后第 %d 次重试: %s: %v", delay, job.attempt, job.msg.Subject, err)
+	q.wg.Add(1)
+	time.AfterFunc(delay, func() {
+		defer q.wg.Done()
+		q.push(job)
+	})
+}
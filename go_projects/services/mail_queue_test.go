@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpSink 本地 SMTP 服务器，前 failures 次 MAIL FROM 返回 451，之后正常收信
+type smtpSink struct {
+	listener net.Listener
+	stall    bool // 接受连接后不发送问候，模拟卡住的服务器
+
+	mu        sync.Mutex
+	failures  int
+	attempts  int
+	delivered []string
+}
+
+func newSMTPSink(t *testing.T, failures int, stall bool) *smtpSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &smtpSink{listener: listener, failures: failures, stall: stall}
+	go sink.serve()
+	t.Cleanup(func() { listener.Close() })
+	return sink
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+	if s.stall {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		io.Copy(io.Discard, conn)
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 sink ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 sink")
+		case strings.HasPrefix(command, "MAIL FROM"):
+			s.mu.Lock()
+			s.attempts++
+			fail := s.failures > 0
+			if fail {
+				s.failures--
+			}
+			s.mu.Unlock()
+			if fail {
+				reply("451 temporary failure")
+				continue
+			}
+			reply("250 ok")
+		case strings.HasPrefix(command, "DATA"):
+			reply("354 go ahead")
+			var body strings.Builder
+			for {
+				data, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if data == ".\r\n" {
+					break
+				}
+				body.WriteString(data)
+			}
+			s.mu.Lock()
+			s.delivered = append(s.delivered, body.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case strings.HasPrefix(command, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpSink) stats() (int, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts, append([]string(nil), s.delivered...)
+}
+
+func TestMailQueueRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int
+		maxRetries    int
+		wantAttempts  int
+		wantDelivered int
+	}{
+		{name: "delivered on first attempt", failures: 0, maxRetries: 3, wantAttempts: 1, wantDelivered: 1},
+		{name: "delivered after transient failures", failures: 2, maxRetries: 3, wantAttempts: 3, wantDelivered: 1},
+		{name: "gives up after max retries", failures: 10, maxRetries: 2, wantAttempts: 3, wantDelivered: 0},
+		{name: "no retries configured", failures: 1, maxRetries: 0, wantAttempts: 1, wantDelivered: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := newSMTPSink(t, tt.failures, false)
+			mailer := NewSMTPMailer(SMTPOptions{
+				Host:       "127.0.0.1",
+				Port:       sink.port(),
+				From:       "noreply@example.com",
+				FromName:   "Blog",
+				Encryption: "none",
+				Timeout:    2 * time.Second,
+			})
+			queue := NewMailQueue(mailer, 10, tt.maxRetries)
+			queue.retryBase = 10 * time.Millisecond
+
+			if !queue.Enqueue(&MailMessage{To: []string{"reader@example.com"}, Subject: "新回复", Text: "hello"}) {
+				t.Fatal("Enqueue() = false, want true")
+			}
+			done := make(chan struct{})
+			go func() {
+				queue.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("queue did not drain")
+			}
+
+			attempts, delivered := sink.stats()
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(delivered) != tt.wantDelivered {
+				t.Fatalf("delivered %d messages, want %d", len(delivered), tt.wantDelivered)
+			}
+			for _, body := range delivered {
+				if !strings.Contains(body, "To: reader@example.com") || !strings.Contains(body, "Subject: =?utf-8?") {
+					t.Errorf("unexpected message headers:\n%s", body)
+				}
+			}
+		})
+	}
+}
+
+func TestMailQueueDropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	queue := NewMailQueue(mailerFunc(func(*MailMessage) error {
+		<-block
+		return nil
+	}), 1, 0)
+	defer func() {
+		close(block)
+		queue.Wait()
+	}()
+
+	msg := &MailMessage{To: []string{"a@example.com"}, Subject: "s", Text: "t"}
+	queue.Enqueue(msg) // 由发送协程取出后阻塞
+	time.Sleep(20 * time.Millisecond)
+	if !queue.Enqueue(msg) {
+		t.Fatal("second Enqueue() = false, want true (fits in buffer)")
+	}
+	if queue.Enqueue(msg) {
+		t.Fatal("third Enqueue() = true, want false (queue full)")
+	}
+}
+
+func TestSMTPMailerTimesOutOnStalledServer(t *testing.T) {
+	for _, encryption := range []string{"none", "starttls", "tls"} {
+		t.Run(encryption, func(t *testing.T) {
+			sink := newSMTPSink(t, 0, true)
+			mailer := NewSMTPMailer(SMTPOptions{
+				Host:       "127.0.0.1",
+				Port:       sink.port(),
+				From:       "noreply@example.com",
+				Encryption: encryption,
+				Timeout:    100 * time.Millisecond,
+			})
+
+			start := time.Now()
+			err := mailer.Send(&MailMessage{To: []string{"reader@example.com"}, Subject: "s", Text: "t"})
+			if err == nil {
+				t.Fatal("Send() succeeded against a stalled server")
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Send() took %s, want it bounded by the connection deadline", elapsed)
+			}
+		})
+	}
+}
+
+type mailerFunc func(*MailMessage) error
+
+func (f mailerFunc) Send(msg *MailMessage) error { return f(msg) }
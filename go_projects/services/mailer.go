@@ -0,0 +1,210 @@
+package services
+
+import (
+	"blog-system/config"
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MailMessage 一封待发送的邮件，HTML 与纯文本正文至少填写一个
+type MailMessage struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // 额外的邮件头，如 List-Unsubscribe
+}
+
+// Mailer 邮件发送通道
+type Mailer interface {
+	Send(msg *MailMessage) error
+}
+
+// SMTPOptions SMTP 连接参数
+type SMTPOptions struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	From       string
+	FromName   string
+	Encryption string // none, starttls, tls
+	Timeout    time.Duration
+}
+
+type smtpMailer struct {
+	opts SMTPOptions
+}
+
+func NewSMTPMailer(opts SMTPOptions) Mailer {
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &smtpMailer{opts: opts}
+}
+
+// smtpOptionsFromConfig 读取配置中的 SMTP 参数
+func smtpOptionsFromConfig() SMTPOptions {
+	cfg := config.AppConfig
+	return SMTPOptions{
+		Host:       cfg.MailHost,
+		Port:       cfg.MailPort,
+		Username:   cfg.MailUsername,
+		Password:   cfg.MailPassword,
+		From:       cfg.MailFrom,
+		FromName:   cfg.MailFromName,
+		Encryption: cfg.MailEncryption,
+	}
+}
+
+func (m *smtpMailer) Send(msg *MailMessage) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("mail has no recipients")
+	}
+	body, err := m.build(msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := m.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.opts.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.opts.From); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *smtpMailer) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.opts.Host, strconv.Itoa(m.opts.Port))
+	tlsConfig := &tls.Config{ServerName: m.opts.Host}
+	dialer := &net.Dialer{Timeout: m.opts.Timeout}
+
+	var conn net.Conn
+	var err error
+	if m.opts.Encryption == "tls" {
+		// 超时同时覆盖 TCP 连接和 TLS 握手
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 整个会话的读写截止时间，服务器卡在任何一步都不会阻塞发送队列
+	if err := conn.SetDeadline(time.Now().Add(2 * m.opts.Timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, m.opts.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if m.opts.Encryption == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// build 生成 multipart/alternative 格式的邮件正文
+func (m *smtpMailer) build(msg *MailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	from := mail.Address{Name: m.opts.FromName, Address: m.opts.From}
+
+	headers := []string{
+		"From: " + from.String(),
+		"To: " + strings.Join(msg.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID(m.opts.From),
+		"MIME-Version: 1.0",
+	}
+	for key, value := range msg.Headers {
+		headers = append(headers, key+": "+value)
+	}
+
+	// multipart.Writer 只在创建分段时写入 buf，因此可以先写邮件头
+	writer := multipart.NewWriter(&buf)
+	headers = append(headers, "Content-Type: multipart/alternative; boundary="+writer.Boundary())
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, part := range parts {
+		if part.body == "" {
+			continue
+		}
+		pw, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	random := make([]byte, 12)
+	rand.Read(random)
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">"
+}
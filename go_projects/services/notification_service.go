@@ -0,0 +1,199 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/repositories"
+	"blog-system/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsubscribeToken = errors.New("invalid unsubscribe link")
+
+type NotificationService interface {
+	// NotifyCommentPending 通知管理员有新评论待审核
+	NotifyCommentPending(comment *models.Comment)
+	// NotifyReplyApproved 回复通过审核后通知被回复的评论者
+	NotifyReplyApproved(reply *models.Comment)
+	// VerifyUnsubscribe 只校验退订链接，用于展示确认页面
+	VerifyUnsubscribe(emailHash, token string) error
+	// Unsubscribe 校验退订链接并记录退订
+	Unsubscribe(emailHash, token string) error
+}
+
+type notificationService struct {
+	queue           *MailQueue
+	commentRepo     repositories.CommentRepository
+	targets         *commentTargetResolver
+	userRepo        repositories.UserRepository
+	unsubscribeRepo repositories.UnsubscribeRepository
+}
+
+// NewNotificationService queue 为 nil（未启用邮件）时只保留退订功能，通知直接跳过
+func NewNotificationService(queue *MailQueue) NotificationService {
+	return &notificationService{
+		queue:           queue,
+		commentRepo:     repositories.NewCommentRepository(),
+		targets:         newCommentTargetResolver(),
+		userRepo:        repositories.NewUserRepository(),
+		unsubscribeRepo: repositories.NewUnsubscribeRepository(),
+	}
+}
+
+type commentPendingEmail struct {
+	emailBase
+	TargetTitle string
+	Comment     *models.Comment
+	SpamReasons []string
+	ModerateURL string
+}
+
+type commentReplyEmail struct {
+	emailBase
+	TargetTitle string
+	Parent      *models.Comment
+	Comment     *models.Comment
+	CommentURL  string
+}
+
+func (s *notificationService) NotifyCommentPending(comment *models.Comment) {
+	if s.queue == nil {
+		return
+	}
+
+	var reasons []string
+	if len(comment.SpamReasons) > 0 {
+		json.Unmarshal(comment.SpamReasons, &reasons)
+	}
+	title, _ := s.targetInfo(comment)
+
+	for _, to := range s.adminRecipients() {
+		subject := fmt.Sprintf("[%s] 新评论待审核：%s", config.AppConfig.SiteName, title)
+		s.send(to, subject, "comment_pending", func(base emailBase) interface{} {
+			return commentPendingEmail{
+				emailBase:   base,
+				TargetTitle: title,
+				Comment:     comment,
+				SpamReasons: reasons,
+				ModerateURL: siteBaseURL() + "/admin",
+			}
+		})
+	}
+}
+
+func (s *notificationService) NotifyReplyApproved(reply *models.Comment) {
+	if s.queue == nil || reply.ParentID == nil {
+		return
+	}
+	parent, err := s.commentRepo.FindByID(*reply.ParentID)
+	if err != nil || parent.Email == "" {
+		return
+	}
+	// 自己回复自己不通知
+	if strings.EqualFold(strings.TrimSpace(parent.Email), strings.TrimSpace(reply.Email)) {
+		return
+	}
+
+	title, path := s.targetInfo(reply)
+	subject := fmt.Sprintf("[%s] %s 回复了你在《%s》的评论", config.AppConfig.SiteName, reply.Author, title)
+	s.send(parent.Email, subject, "comment_reply", func(base emailBase) interface{} {
+		return commentReplyEmail{
+			emailBase:   base,
+			TargetTitle: title,
+			Parent:      parent,
+			Comment:     reply,
+			CommentURL:  fmt.Sprintf("%s%s#comment-%d", siteBaseURL(), path, reply.ID),
+		}
+	})
+}
+
+// send 跳过已退订的邮箱，渲染模板后放入发送队列
+func (s *notificationService) send(to, subject, template string, build func(base emailBase) interface{}) {
+	hash := utils.EmailHash(to)
+	if unsubscribed, err := s.unsubscribeRepo.IsUnsubscribed(hash); err != nil || unsubscribed {
+		return
+	}
+
+	unsubscribeURL := unsubscribeLink(hash)
+	html, text, err := renderEmail(template, build(newEmailBase(subject, unsubscribeURL)))
+	if err != nil {
+		log.Printf("渲染邮件模板 %s 失败: %v", template, err)
+		return
+	}
+
+	s.queue.Enqueue(&MailMessage{
+		To:      []string{to},
+		Subject: subject,
+		HTML:    html,
+		Text:    text,
+		Headers: map[string]string{
+			// RFC 8058 一键退订
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
+
+// adminRecipients 优先使用配置的收件人，否则发送给所有管理员账号
+func (s *notificationService) adminRecipients() []string {
+	if len(config.AppConfig.MailAdminEmails) > 0 {
+		return config.AppConfig.MailAdminEmails
+	}
+	admins, err := s.userRepo.FindByRole(models.RoleAdmin)
+	if err != nil {
+		return nil
+	}
+	recipients := make([]string, 0, len(admins))
+	for _, admin := range admins {
+		if admin.Email != "" {
+			recipients = append(recipients, admin.Email)
+		}
+	}
+	return recipients
+}
+
+// targetInfo 评论对象的标题和站内路径，对象已删除时退化为类型加 ID
+func (s *notificationService) targetInfo(comment *models.Comment) (string, string) {
+	target, err := s.targets.ResolveComment(comment)
+	if err != nil {
+		return comment.TargetType + "#" + strconv.FormatUint(uint64(comment.TargetID), 10), "/"
+	}
+	return target.Title, target.Path
+}
+
+func (s *notificationService) VerifyUnsubscribe(emailHash, token string) error {
+	if emailHash == "" || !hmac.Equal([]byte(token), []byte(unsubscribeToken(emailHash))) {
+		return ErrUnsubscribeToken
+	}
+	return nil
+}
+
+func (s *notificationService) Unsubscribe(emailHash, token string) error {
+	if err := s.VerifyUnsubscribe(emailHash, token); err != nil {
+		return err
+	}
+	return s.unsubscribeRepo.Create(emailHash)
+}
+
+// unsubscribeToken 用 JWT 密钥对邮箱哈希签名，退订链接无需登录且不暴露邮箱
+func unsubscribeToken(emailHash string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWTSecret))
+	mac.Write([]byte("unsubscribe:" + emailHash))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+func unsubscribeLink(emailHash string) string {
+	query := url.Values{}
+	query.Set("e", emailHash)
+	query.Set("t", unsubscribeToken(emailHash))
+	return siteBaseURL() + "/api/notifications/unsubscribe?" + query.Encode()
+}
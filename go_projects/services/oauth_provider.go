@@ -0,0 +1,496 @@
+package services
+
+import (
+	"blog-system/config"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的提供方类型，对应 oauth.providers[].type
+const (
+	OAuthTypeOIDC   = "oidc"
+	OAuthTypeOAuth2 = "oauth2"
+	OAuthTypeGitHub = "github"
+)
+
+const (
+	// oauthResponseLimit 读取提供方响应的最大字节数
+	oauthResponseLimit = 1 << 20
+	// jwksRefreshInterval 遇到未知 kid 时重新拉取 JWKS 的最短间隔，防止被伪造的 kid 拖垮
+	jwksRefreshInterval = time.Minute
+)
+
+// oauthIdentity 从提供方取得的用户信息
+type oauthIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+}
+
+// oauthProvider 一个已配置的提供方；OIDC 的端点和签名公钥在首次使用时获取并缓存
+type oauthProvider struct {
+	cfg    config.OAuthProviderConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovered  bool
+	issuer      string
+	authURL     string
+	tokenURL    string
+	userInfoURL string
+	jwksURL     string
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+type oauthTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type idTokenClaims struct {
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // 部分提供方返回字符串 "true"
+	Name              string      `json:"name"`
+	PreferredUsername string      `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+var (
+	oauthProviderRegistry     map[string]*oauthProvider
+	oauthProviderRegistryOnce sync.Once
+)
+
+// oauthProviders 按配置构建的提供方，进程内共享以复用 discovery 和 JWKS 缓存
+func oauthProviders() map[string]*oauthProvider {
+	oauthProviderRegistryOnce.Do(func() {
+		oauthProviderRegistry = make(map[string]*oauthProvider)
+		for _, cfg := range config.AppConfig.OAuthProviders {
+			if cfg.Name == "" || cfg.ClientID == "" {
+				continue
+			}
+			if cfg.Type == "" {
+				cfg.Type = OAuthTypeOIDC
+			}
+			if cfg.DisplayName == "" {
+				cfg.DisplayName = cfg.Name
+			}
+			oauthProviderRegistry[cfg.Name] = newOAuthProvider(cfg, nil)
+		}
+	})
+	return oauthProviderRegistry
+}
+
+// newOAuthProvider client 为 nil 时使用默认超时的客户端
+func newOAuthProvider(cfg config.OAuthProviderConfig, client *http.Client) *oauthProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	p := &oauthProvider{
+		cfg:         cfg,
+		client:      client,
+		issuer:      strings.TrimRight(cfg.Issuer, "/"),
+		authURL:     cfg.AuthURL,
+		tokenURL:    cfg.TokenURL,
+		userInfoURL: cfg.UserInfoURL,
+	}
+	if cfg.Type == OAuthTypeGitHub {
+		p.authURL = firstNonEmpty(p.authURL, "https://github.com/login/oauth/authorize")
+		p.tokenURL = firstNonEmpty(p.tokenURL, "https://github.com/login/oauth/access_token")
+		p.userInfoURL = firstNonEmpty(p.userInfoURL, "https://api.github.com/user")
+	}
+	return p
+}
+
+func (p *oauthProvider) scopes() []string {
+	if len(p.cfg.Scopes) > 0 {
+		return p.cfg.Scopes
+	}
+	switch p.cfg.Type {
+	case OAuthTypeGitHub:
+		return []string{"read:user", "user:email"}
+	case OAuthTypeOIDC:
+		return []string{"openid", "email", "profile"}
+	default:
+		return nil
+	}
+}
+
+func (p *oauthProvider) allowSignup() bool {
+	return p.cfg.AllowSignup == nil || *p.cfg.AllowSignup
+}
+
+// redirectURI 在提供方登记的回调地址
+func (p *oauthProvider) redirectURI() string {
+	return siteBaseURL() + "/api/auth/oauth/" + url.PathEscape(p.cfg.Name) + "/callback"
+}
+
+// AuthorizationURL 生成跳转到提供方的授权地址（授权码 + PKCE S256）
+func (p *oauthProvider) AuthorizationURL(state, nonce, codeChallenge string) (string, error) {
+	if err := p.discover(); err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.redirectURI())
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	if scopes := p.scopes(); len(scopes) > 0 {
+		query.Set("scope", strings.Join(scopes, " "))
+	}
+	if p.cfg.Type == OAuthTypeOIDC {
+		query.Set("nonce", nonce)
+	}
+
+	separator := "?"
+	if strings.Contains(p.authURL, "?") {
+		separator = "&"
+	}
+	return p.authURL + separator + query.Encode(), nil
+}
+
+// Identity 用授权码换取令牌并读取用户信息；OIDC 会校验 id_token 的签名、issuer、audience 和 nonce
+func (p *oauthProvider) Identity(code, codeVerifier, nonce string) (*oauthIdentity, error) {
+	if err := p.discover(); err != nil {
+		return nil, err
+	}
+	token, err := p.exchange(code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.cfg.Type {
+	case OAuthTypeOIDC:
+		return p.oidcIdentity(token, nonce)
+	case OAuthTypeGitHub:
+		return p.githubIdentity(token.AccessToken)
+	default:
+		info, err := p.userInfo(token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		identity := identityFromClaims(info)
+		identity.EmailVerified = identity.EmailVerified || (p.cfg.TrustEmail && identity.Email != "")
+		return identity, nil
+	}
+}
+
+// discover 读取 OIDC discovery 文档，配置中显式填写的端点优先
+func (p *oauthProvider) discover() error {
+	if p.cfg.Type != OAuthTypeOIDC {
+		if p.authURL == "" || p.tokenURL == "" || p.userInfoURL == "" {
+			return fmt.Errorf("oauth provider %s: auth_url, token_url and userinfo_url are required", p.cfg.Name)
+		}
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered {
+		return nil
+	}
+	if p.issuer == "" {
+		return fmt.Errorf("oauth provider %s: issuer is required", p.cfg.Name)
+	}
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := p.getJSON(p.issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return fmt.Errorf("oauth provider %s: discovery failed: %w", p.cfg.Name, err)
+	}
+	// 防止 discovery 文档被替换成其他 issuer
+	if strings.TrimRight(doc.Issuer, "/") != p.issuer {
+		return fmt.Errorf("oauth provider %s: discovery issuer %q does not match %q", p.cfg.Name, doc.Issuer, p.issuer)
+	}
+	if doc.JWKSURI == "" {
+		return fmt.Errorf("oauth provider %s: discovery document has no jwks_uri", p.cfg.Name)
+	}
+
+	p.issuer = doc.Issuer
+	p.authURL = firstNonEmpty(p.authURL, doc.AuthorizationEndpoint)
+	p.tokenURL = firstNonEmpty(p.tokenURL, doc.TokenEndpoint)
+	p.userInfoURL = firstNonEmpty(p.userInfoURL, doc.UserInfoEndpoint)
+	p.jwksURL = doc.JWKSURI
+	p.discovered = true
+	return nil
+}
+
+func (p *oauthProvider) exchange(code, codeVerifier string) (*oauthTokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURI())
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token oauthTokenResponse
+	status, err := p.doJSON(req, &token)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	// GitHub 出错时也返回 200，错误放在 error 字段中
+	if token.Error != "" {
+		return nil, fmt.Errorf("token exchange failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if status != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("token exchange failed: status %d", status)
+	}
+	return &token, nil
+}
+
+func (p *oauthProvider) oidcIdentity(token *oauthTokenResponse, nonce string) (*oauthIdentity, error) {
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(token.IDToken, claims, p.signingKey,
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if claims.Subject == "" || !hmac.Equal([]byte(claims.Nonce), []byte(nonce)) {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+
+	identity := &oauthIdentity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claimBool(claims.EmailVerified),
+		Name:          claims.Name,
+		Username:      claims.PreferredUsername,
+	}
+	// id_token 中没有邮箱时再查询 userinfo，sub 必须一致
+	if identity.Email == "" && p.userInfoURL != "" {
+		info, err := p.userInfo(token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		extra := identityFromClaims(info)
+		if extra.Subject != identity.Subject {
+			return nil, errors.New("userinfo subject does not match id_token")
+		}
+		identity.Email, identity.EmailVerified = extra.Email, extra.EmailVerified
+		identity.Name = firstNonEmpty(identity.Name, extra.Name)
+		identity.Username = firstNonEmpty(identity.Username, extra.Username)
+	}
+	identity.EmailVerified = identity.EmailVerified || (p.cfg.TrustEmail && identity.Email != "")
+	return identity, nil
+}
+
+// githubIdentity GitHub 的 /user 只返回公开邮箱，已验证的邮箱需要另外查询 /user/emails
+func (p *oauthProvider) githubIdentity(accessToken string) (*oauthIdentity, error) {
+	info, err := p.userInfo(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	identity := identityFromClaims(info)
+	identity.Email, identity.EmailVerified = "", false
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	emailsURL := strings.TrimSuffix(p.userInfoURL, "/user") + "/user/emails"
+	if err := p.getJSON(emailsURL, accessToken, &emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		if e.Verified && (e.Primary || identity.Email == "") {
+			identity.Email, identity.EmailVerified = e.Email, true
+		}
+	}
+	return identity, nil
+}
+
+func (p *oauthProvider) userInfo(accessToken string) (map[string]interface{}, error) {
+	var info map[string]interface{}
+	if err := p.getJSON(p.userInfoURL, accessToken, &info); err != nil {
+		return nil, fmt.Errorf("userinfo request failed: %w", err)
+	}
+	return info, nil
+}
+
+// signingKey 按 id_token 头部的 kid 查找公钥，找不到时重新拉取一次 JWKS（提供方轮换密钥）
+func (p *oauthProvider) signingKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	p.keysFetched = time.Now()
+	if err := p.getJSON(p.jwksURL, "", &set); err != nil {
+		return nil, fmt.Errorf("jwks request failed: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey 没有 kid 且只有一个公钥时直接使用该公钥
+func (p *oauthProvider) lookupKey(kid string) *rsa.PublicKey {
+	if key, ok := p.keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+func (p *oauthProvider) getJSON(target, accessToken string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	status, err := p.doJSON(req, out)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", status, target)
+	}
+	return nil
+}
+
+func (p *oauthProvider) doJSON(req *http.Request, out interface{}) (int, error) {
+	// GitHub API 要求带 User-Agent
+	req.Header.Set("User-Agent", config.AppConfig.SiteName)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oauthResponseLimit))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, out); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
+
+// identityFromClaims 按 OIDC 标准字段和 GitHub、Gitea 等常见字段读取用户信息
+func identityFromClaims(info map[string]interface{}) *oauthIdentity {
+	identity := &oauthIdentity{
+		Subject:       claimString(info, "sub", "id"),
+		Email:         claimString(info, "email"),
+		EmailVerified: claimBool(info["email_verified"]),
+		Name:          claimString(info, "name"),
+		Username:      claimString(info, "preferred_username", "login", "username"),
+	}
+	return identity
+}
+
+func claimString(info map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := info[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+func claimBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"blog-system/models"
+	"blog-system/utils"
+	"time"
+)
+
+// PublicComment 公开接口返回的评论，不包含邮箱和 IP，头像由邮箱哈希生成
+type PublicComment struct {
+	ID          uint      `json:"id"`
+	TargetType  string    `json:"target_type"`
+	TargetID    uint      `json:"target_id"`
+	ParentID    *uint     `json:"parent_id"`
+	Author      string    `json:"author"`
+	Website     string    `json:"website"`
+	Avatar      string    `json:"avatar"`
+	Content     string    `json:"content"`
+	ContentHTML string    `json:"content_html"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func NewPublicComment(comment models.Comment) PublicComment {
+	return PublicComment{
+		ID:          comment.ID,
+		TargetType:  comment.TargetType,
+		TargetID:    comment.TargetID,
+		ParentID:    comment.ParentID,
+		Author:      comment.Author,
+		Website:     comment.Website,
+		Avatar:      utils.AvatarURL(comment.Email),
+		Content:     comment.Content,
+		ContentHTML: comment.ContentHTML,
+		CreatedAt:   comment.CreatedAt,
+	}
+}
+
+func newPublicComments(comments []models.Comment) []PublicComment {
+	results := make([]PublicComment, 0, len(comments))
+	for _, comment := range comments {
+		results = append(results, NewPublicComment(comment))
+	}
+	return results
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter 进程内的固定窗口计数器，按 key（如用户 ID、IP）限制窗口内的次数
+type rateLimiter struct {
+	mu     sync.Mutex
+	window time.Duration
+	hits   map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(window time.Duration) *rateLimiter {
+	return &rateLimiter{window: window, hits: make(map[string]*rateWindow)}
+}
+
+// Allow 记录一次请求，窗口内已达到 limit 次时返回 false
+func (l *rateLimiter) Allow(key string, limit int) bool {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for k, w := range l.hits {
+		if now.Sub(w.start) >= l.window {
+			delete(l.hits, k)
+		}
+	}
+	w, ok := l.hits[key]
+	if !ok {
+		w = &rateWindow{start: now}
+		l.hits[key] = w
+	}
+	if w.count >= limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// Reset 清除 key 的计数，用于只限制连续失败的场景
+func (l *rateLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.hits, key)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"sort"
+)
+
+// 权限，路由通过 middleware.RequirePermission 检查
+const (
+	PermArticleWrite    = "article.write"    // 撰写文章，修改、删除自己的文章
+	PermArticlePublish  = "article.publish"  // 发布文章；没有该权限时只能保存草稿
+	PermArticleManage   = "article.manage"   // 修改、删除他人的文章
+	PermCommentModerate = "comment.moderate" // 审核、删除评论，查看修改记录
+	PermTaxonomyManage  = "taxonomy.manage"  // 管理分类、标签及标签别名
+	PermMusicManage     = "music.manage"     // 管理音乐和歌单
+	PermLinkManage      = "link.manage"      // 管理友情链接
+	PermMediaUpload     = "media.upload"     // 上传文件和图片
+	PermMediaManage     = "media.manage"     // 删除上传的文件
+	PermSiteManage      = "site.manage"      // 链接检查、评论导入等站点维护
+	PermUserManage      = "user.manage"      // 管理用户和角色分配
+)
+
+// AllPermissions 全部权限，管理员默认拥有
+var AllPermissions = []string{
+	PermArticleWrite,
+	PermArticlePublish,
+	PermArticleManage,
+	PermCommentModerate,
+	PermTaxonomyManage,
+	PermMusicManage,
+	PermLinkManage,
+	PermMediaUpload,
+	PermMediaManage,
+	PermSiteManage,
+	PermUserManage,
+}
+
+// defaultRolePermissions 内置角色的默认权限，可以通过 rbac.roles 覆盖
+var defaultRolePermissions = map[string][]string{
+	models.RoleAdmin: AllPermissions,
+	models.RoleEditor: {
+		PermArticleWrite, PermArticlePublish, PermArticleManage,
+		PermCommentModerate, PermTaxonomyManage, PermMediaUpload, PermMediaManage,
+	},
+	models.RoleAuthor:    {PermArticleWrite, PermArticlePublish, PermMediaUpload},
+	models.RoleModerator: {PermCommentModerate},
+	models.RoleReader:    {},
+}
+
+// RoleInfo 角色及其权限
+type RoleInfo struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+// RolePermissions 角色拥有的权限，未知角色没有任何权限
+func RolePermissions(role string) []string {
+	if perms, ok := config.AppConfig.RBACRoles[role]; ok && role != models.RoleAdmin {
+		return perms
+	}
+	return defaultRolePermissions[role]
+}
+
+// HasPermission 角色是否拥有全部指定的权限
+func HasPermission(role string, perms ...string) bool {
+	granted := RolePermissions(role)
+	for _, perm := range perms {
+		found := false
+		for _, g := range granted {
+			if g == perm {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidRole 是否为内置角色
+func ValidRole(role string) bool {
+	_, ok := defaultRolePermissions[role]
+	return ok
+}
+
+// Roles 全部角色及当前生效的权限
+func Roles() []RoleInfo {
+	roles := make([]RoleInfo, 0, len(defaultRolePermissions))
+	for name := range defaultRolePermissions {
+		roles = append(roles, RoleInfo{Name: name, Permissions: RolePermissions(name)})
+	}
+	// 按权限从多到少排列
+	sort.SliceStable(roles, func(i, j int) bool {
+		if len(roles[i].Permissions) != len(roles[j].Permissions) {
+			return len(roles[i].Permissions) > len(roles[j].Permissions)
+		}
+		return roles[i].Name < roles[j].Name
+	})
+	return roles
+}
+
+// DefaultRole 新注册用户的角色
+func DefaultRole() string {
+	if ValidRole(config.AppConfig.RBACDefaultRole) && config.AppConfig.RBACDefaultRole != models.RoleAdmin {
+		return config.AppConfig.RBACDefaultRole
+	}
+	return models.RoleReader
+}
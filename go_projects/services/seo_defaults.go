@@ -0,0 +1,111 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"regexp"
+	"strings"
+)
+
+// seoDescriptionLength 自动生成的 meta description 最多保留的字符数
+const seoDescriptionLength = 160
+
+var (
+	markdownImagePattern    = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownLinkTextPattern = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownSyntaxPattern   = regexp.MustCompile("(?m)^\\s{0,3}(?:#{1,6}|>|[-*+]|\\d+\\.)\\s+|[*_~`]+|<[^>]+>")
+)
+
+// seoFallback 各字段为空时使用的默认值
+type seoFallback struct {
+	title       string
+	description string
+	keywords    string
+	image       string
+	path        string // 站内路径，配置了 site.url 时拼成 canonical
+}
+
+// effective 返回补全空字段后的副本，编辑者填写的值保持不变；
+// 存储的 SEO 字段不被改写，编辑页读回后原样保存不会把默认值固化进数据库
+func (f seoFallback) effective(stored models.SEO) *models.SEO {
+	seo := stored
+	if seo.MetaTitle == "" {
+		seo.MetaTitle = f.title
+	}
+	if seo.MetaDescription == "" {
+		seo.MetaDescription = summarize(f.description, seoDescriptionLength)
+	}
+	if seo.Keywords == "" {
+		seo.Keywords = f.keywords
+	}
+	if seo.OGImage == "" {
+		seo.OGImage = f.image
+	}
+	if seo.CanonicalURL == "" && f.path != "" && config.AppConfig.SiteURL != "" {
+		seo.CanonicalURL = config.AppConfig.SiteURL + f.path
+	}
+	return &seo
+}
+
+// applyArticleSEO 标题、摘要（无摘要时取正文）、标签和封面图作为文章 SEO 默认值
+func applyArticleSEO(article *models.Article) {
+	description := article.Excerpt
+	if description == "" {
+		description = article.Content
+	}
+	keywords := make([]string, 0, len(article.Tags))
+	for _, tag := range article.Tags {
+		keywords = append(keywords, tag.Name)
+	}
+	article.SEOEffective = seoFallback{
+		title:       article.Title,
+		description: description,
+		keywords:    strings.Join(keywords, ","),
+		image:       article.CoverImage,
+		path:        "/articles/" + article.Slug,
+	}.effective(article.SEO)
+}
+
+func applyCategorySEO(category *models.Category) {
+	category.SEOEffective = seoFallback{
+		title:       category.Name,
+		description: category.Description,
+		keywords:    category.Name,
+	}.effective(category.SEO)
+}
+
+func applyTagSEO(tag *models.Tag) {
+	tag.SEOEffective = seoFallback{
+		title:    tag.Name,
+		keywords: tag.Name,
+	}.effective(tag.SEO)
+}
+
+func applyLabSEO(lab *models.Lab) {
+	description := lab.Description
+	if description == "" {
+		description = lab.Subtitle
+	}
+	lab.SEOEffective = seoFallback{
+		title:       lab.Title,
+		description: description,
+		keywords:    lab.Focus,
+		image:       lab.HeroImage,
+		path:        "/labs/" + lab.Slug,
+	}.effective(lab.SEO)
+}
+
+// summarize 去掉 Markdown 标记后截取前 limit 个字符
+func summarize(markdown string, limit int) string {
+	text := fencedCodePattern.ReplaceAllString(markdown, " ")
+	text = markdownImagePattern.ReplaceAllString(text, " ")
+	text = markdownLinkTextPattern.ReplaceAllString(text, "$1")
+	text = markdownSyntaxPattern.ReplaceAllString(text, "")
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:limit])) + "…"
+}
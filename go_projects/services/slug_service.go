@@ -0,0 +1,205 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/repositories"
+	"blog-system/utils"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+)
+
+// Slug 生成策略
+const (
+	SlugStrategyPinyin        = "pinyin"
+	SlugStrategyTransliterate = "transliterate"
+	SlugStrategyID            = "id"
+	SlugStrategyDate          = "date"
+)
+
+var (
+	ErrSlugInvalid = errors.New("slug may only contain lowercase letters, digits and single hyphens")
+	ErrSlugTaken   = errors.New("slug is already in use")
+)
+
+var customSlugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// SlugStrategy 根据标题生成基础 slug，返回空字符串表示使用数字编号
+type SlugStrategy interface {
+	Base(source string, now time.Time) string
+}
+
+type pinyinSlugStrategy struct{}
+
+func (pinyinSlugStrategy) Base(source string, _ time.Time) string {
+	return slug.Make(strings.Join(utils.PinyinSegments(source), "-"))
+}
+
+type transliterateSlugStrategy struct{}
+
+func (transliterateSlugStrategy) Base(source string, _ time.Time) string {
+	return slug.Make(source)
+}
+
+type idSlugStrategy struct{}
+
+func (idSlugStrategy) Base(string, time.Time) string {
+	return ""
+}
+
+type dateSlugStrategy struct{}
+
+func (dateSlugStrategy) Base(source string, now time.Time) string {
+	base := pinyinSlugStrategy{}.Base(source, now)
+	if base == "" {
+		return now.Format("2006-01-02")
+	}
+	return now.Format("2006-01-02") + "-" + base
+}
+
+var slugStrategies = map[string]SlugStrategy{
+	SlugStrategyPinyin:        pinyinSlugStrategy{},
+	SlugStrategyTransliterate: transliterateSlugStrategy{},
+	SlugStrategyID:            idSlugStrategy{},
+	SlugStrategyDate:          dateSlugStrategy{},
+}
+
+// RegisterSlugStrategy 注册自定义 slug 策略，可在配置中通过名称选用
+func RegisterSlugStrategy(name string, strategy SlugStrategy) {
+	slugStrategies[name] = strategy
+}
+
+type SlugService interface {
+	// Make 按配置的策略生成基础 slug（不检查冲突）
+	Make(source string) string
+	// Validate 校验作者自定义的 slug
+	Validate(custom string) error
+	// Assign 返回在事务内生成唯一 slug 的函数；custom 非空时使用并校验自定义 slug。
+	// 修改已有记录（excludeID 非 0）且策略不根据 source 生成 slug（如数字编号）时返回 nil，保留原 slug
+	Assign(source, custom string, excludeID uint) repositories.SlugFunc
+	// Unique 返回在事务内为 base 追加唯一后缀的函数
+	Unique(base string, excludeID uint) repositories.SlugFunc
+}
+
+type slugService struct {
+	strategy  SlugStrategy
+	maxLength int
+}
+
+func NewSlugService() SlugService {
+	strategy, ok := slugStrategies[config.AppConfig.SlugStrategy]
+	if !ok {
+		strategy = slugStrategies[SlugStrategyPinyin]
+	}
+	return &slugService{strategy: strategy, maxLength: config.AppConfig.SlugMaxLength}
+}
+
+func (s *slugService) Make(source string) string {
+	return s.truncate(s.strategy.Base(source, time.Now()))
+}
+
+func (s *slugService) Validate(custom string) error {
+	if len(custom) > s.maxLength || !customSlugPattern.MatchString(custom) {
+		return ErrSlugInvalid
+	}
+	return nil
+}
+
+func (s *slugService) Assign(source, custom string, excludeID uint) repositories.SlugFunc {
+	if custom == "" && excludeID != 0 && s.Make(source) == "" {
+		return nil
+	}
+	return func(lookup repositories.SlugLookup) (string, error) {
+		if custom != "" {
+			if err := s.Validate(custom); err != nil {
+				return "", err
+			}
+			existing, err := lookup.SlugsWithPrefix(custom, excludeID)
+			if err != nil {
+				return "", err
+			}
+			for _, taken := range existing {
+				if taken == custom {
+					return "", ErrSlugTaken
+				}
+			}
+			return custom, nil
+		}
+
+		base := s.Make(source)
+		if base == "" {
+			// 数字编号使用记录的真实主键，新记录插入后才能确定
+			id := lookup.RecordID()
+			if id == 0 {
+				return "", nil
+			}
+			return s.unique(lookup, strconv.FormatUint(uint64(id), 10), id)
+		}
+		return s.unique(lookup, base, excludeID)
+	}
+}
+
+func (s *slugService) Unique(base string, excludeID uint) repositories.SlugFunc {
+	return func(lookup repositories.SlugLookup) (string, error) {
+		return s.unique(lookup, s.truncate(base), excludeID)
+	}
+}
+
+// unique 在 base 已被占用时追加最小可用的数字后缀：base-2、base-3……
+// 加上后缀超出长度限制时截短 base，截短后的前缀另行查询已占用的 slug
+func (s *slugService) unique(lookup repositories.SlugLookup, base string, excludeID uint) (string, error) {
+	taken := make(map[string]bool)
+	loaded := make(map[string]bool)
+	load := func(prefix string) error {
+		if loaded[prefix] {
+			return nil
+		}
+		loaded[prefix] = true
+		existing, err := lookup.SlugsWithPrefix(prefix, excludeID)
+		if err != nil {
+			return err
+		}
+		for _, value := range existing {
+			taken[value] = true
+		}
+		return nil
+	}
+
+	if err := load(base); err != nil {
+		return "", err
+	}
+	if !taken[base] {
+		return base, nil
+	}
+
+	for n := 2; ; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		trimmed := base
+		if s.maxLength > 0 && len(trimmed)+len(suffix) > s.maxLength {
+			trimmed = strings.TrimRight(trimmed[:s.maxLength-len(suffix)], "-")
+			if err := load(trimmed); err != nil {
+				return "", err
+			}
+		}
+		candidate := trimmed + suffix
+		if !taken[candidate] {
+			return candidate, nil
+		}
+	}
+}
+
+// truncate 按最大长度截断，尽量在连字符处断开
+func (s *slugService) truncate(value string) string {
+	if s.maxLength <= 0 || len(value) <= s.maxLength {
+		return value
+	}
+	cut := value[:s.maxLength]
+	if i := strings.LastIndex(cut, "-"); i > s.maxLength/2 {
+		cut = cut[:i]
+	}
+	return strings.Trim(cut, "-")
+}
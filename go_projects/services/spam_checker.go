@@ -0,0 +1,311 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/repositories"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 各检查项命中时的分数，与配置中的阈值配合使用
+const (
+	spamScoreBlocklist     = 5
+	spamScorePerExtraLink  = 3
+	spamScoreHoneypot      = 10
+	spamScoreTooFast       = 4
+	spamScoreUnknownTiming = 2
+	spamScoreDuplicate     = 6
+	spamScoreAkismet       = 8
+	spamScoreAkismetDrop   = 10
+)
+
+// CommentFormTokenTTL 评论表单令牌的有效期，约为一次阅读会话的长度，过期后按“渲染时间未知”计分
+const CommentFormTokenTTL = 2 * time.Hour
+
+var commentLinkPattern = regexp.MustCompile(`(?i)\bhttps?://|\bwww\.`)
+
+// CommentMeta 评论提交时的表单和请求信息，不落库
+type CommentMeta struct {
+	Honeypot  string // 隐藏字段，正常用户不会填写
+	FormToken string // 渲染表单时由服务端签发的一次性令牌，见 IssueCommentFormToken
+	UserAgent string
+	Referrer  string
+	Permalink string // 评论所在页面地址，由评论服务填充
+}
+
+// SpamSubmission 交给反垃圾检查项的一次评论提交
+type SpamSubmission struct {
+	Comment *models.Comment
+	CommentMeta
+}
+
+// SpamResult 单个检查项的结果
+type SpamResult struct {
+	Score   float64
+	Reasons []string
+}
+
+// SpamChecker 反垃圾检查项
+type SpamChecker interface {
+	Name() string
+	Check(sub *SpamSubmission) (SpamResult, error)
+}
+
+// SpamVerdict 汇总结果，Status 为评论应进入的状态
+type SpamVerdict struct {
+	Status  string
+	Score   float64
+	Reasons []string
+}
+
+// SpamPipeline 依次运行所有检查项并按阈值给出结论
+type SpamPipeline struct {
+	checkers         []SpamChecker
+	approveThreshold float64
+	rejectThreshold  float64
+}
+
+func NewSpamPipeline(approveThreshold, rejectThreshold float64, checkers ...SpamChecker) *SpamPipeline {
+	return &SpamPipeline{
+		checkers:         checkers,
+		approveThreshold: approveThreshold,
+		rejectThreshold:  rejectThreshold,
+	}
+}
+
+// NewDefaultSpamPipeline 按配置组装检查项；未启用时返回 nil，评论全部进入待审核
+func NewDefaultSpamPipeline(repo repositories.CommentRepository) *SpamPipeline {
+	cfg := config.AppConfig
+	if !cfg.SpamEnabled {
+		return nil
+	}
+
+	checkers := []SpamChecker{
+		&honeypotChecker{},
+		&submitTimingChecker{min: time.Duration(cfg.SpamMinSubmitSeconds) * time.Second},
+		&linkCountChecker{max: cfg.SpamMaxLinks},
+		&duplicateChecker{repo: repo, window: time.Duration(cfg.SpamDuplicateHours) * time.Hour},
+	}
+	if blocklist, err := newBlocklistChecker(cfg.SpamBlocklist); err != nil {
+		log.Printf("反垃圾屏蔽词配置无效，已忽略: %v", err)
+	} else {
+		checkers = append(checkers, blocklist)
+	}
+	if cfg.AkismetAPIKey != "" {
+		checkers = append(checkers, NewAkismetChecker(cfg.AkismetEndpoint, cfg.AkismetAPIKey, cfg.AkismetBlog, nil))
+	}
+
+	return NewSpamPipeline(cfg.SpamApproveThreshold, cfg.SpamRejectThreshold, checkers...)
+}
+
+// Evaluate 检查项出错时记录日志并跳过，不影响评论提交
+func (p *SpamPipeline) Evaluate(sub *SpamSubmission) SpamVerdict {
+	verdict := SpamVerdict{Reasons: []string{}}
+	for _, checker := range p.checkers {
+		result, err := checker.Check(sub)
+		if err != nil {
+			log.Printf("反垃圾检查 %s 失败: %v", checker.Name(), err)
+			continue
+		}
+		verdict.Score += result.Score
+		for _, reason := range result.Reasons {
+			verdict.Reasons = append(verdict.Reasons, checker.Name()+": "+reason)
+		}
+	}
+
+	switch {
+	case verdict.Score >= p.rejectThreshold:
+		verdict.Status = models.CommentStatusSpam
+	case verdict.Score < p.approveThreshold:
+		verdict.Status = models.CommentStatusApproved
+	default:
+		verdict.Status = models.CommentStatusPending
+	}
+	return verdict
+}
+
+type honeypotChecker struct{}
+
+func (c *honeypotChecker) Name() string { return "honeypot" }
+
+func (c *honeypotChecker) Check(sub *SpamSubmission) (SpamResult, error) {
+	if strings.TrimSpace(sub.Honeypot) == "" {
+		return SpamResult{}, nil
+	}
+	return SpamResult{Score: spamScoreHoneypot, Reasons: []string{"hidden field was filled in"}}, nil
+}
+
+type submitTimingChecker struct {
+	min time.Duration
+}
+
+func (c *submitTimingChecker) Name() string { return "timing" }
+
+func (c *submitTimingChecker) Check(sub *SpamSubmission) (SpamResult, error) {
+	if sub.FormToken == "" {
+		return SpamResult{Score: spamScoreUnknownTiming, Reasons: []string{"form token missing"}}, nil
+	}
+	form, ok := parseCommentFormToken(sub.FormToken)
+	if !ok {
+		return SpamResult{Score: spamScoreUnknownTiming, Reasons: []string{"form token invalid or expired"}}, nil
+	}
+	if !form.boundTo(sub.Comment) {
+		return SpamResult{Score: spamScoreUnknownTiming, Reasons: []string{"form token was issued for another target"}}, nil
+	}
+	elapsed := time.Since(form.IssuedAt)
+	if elapsed < c.min {
+		return SpamResult{
+			Score:   spamScoreTooFast,
+			Reasons: []string{fmt.Sprintf("submitted %.1fs after the form was shown", elapsed.Seconds())},
+		}, nil
+	}
+	return SpamResult{}, nil
+}
+
+// commentFormToken 表单令牌中受签名保护的内容
+type commentFormToken struct {
+	IssuedAt   time.Time
+	Nonce      string
+	TargetType string
+	TargetID   uint
+}
+
+// boundTo 令牌是否签发给该评论所属的对象
+func (t *commentFormToken) boundTo(comment *models.Comment) bool {
+	return t.TargetType == comment.TargetType && t.TargetID == comment.TargetID
+}
+
+// IssueCommentFormToken 签发评论表单令牌，内容为签发时间、一次性 nonce、评论对象和 HMAC 签名；
+// 提交时据此计算填写耗时，客户端无法伪造更早的渲染时间，也不能把令牌挪用到其他对象或重复使用
+func IssueCommentFormToken(target *CommentTarget) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%d.%s.%s.%d", time.Now().Unix(), hex.EncodeToString(nonce), target.Type, target.ID)
+	return payload + "." + commentFormSignature(payload), nil
+}
+
+// parseCommentFormToken 校验签名并解析令牌，超过有效期的令牌视为无效
+func parseCommentFormToken(token string) (*commentFormToken, bool) {
+	cut := strings.LastIndex(token, ".")
+	if cut < 0 {
+		return nil, false
+	}
+	payload, signature := token[:cut], token[cut+1:]
+	if !hmac.Equal([]byte(signature), []byte(commentFormSignature(payload))) {
+		return nil, false
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 {
+		return nil, false
+	}
+	issued, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	targetID, err := strconv.ParseUint(parts[3], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	issuedAt := time.Unix(issued, 0)
+	if age := time.Since(issuedAt); age < 0 || age > CommentFormTokenTTL {
+		return nil, false
+	}
+	return &commentFormToken{IssuedAt: issuedAt, Nonce: parts[1], TargetType: parts[2], TargetID: uint(targetID)}, true
+}
+
+func commentFormSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte("comment-form:"+config.AppConfig.JWTSecret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type linkCountChecker struct {
+	max int
+}
+
+func (c *linkCountChecker) Name() string { return "links" }
+
+func (c *linkCountChecker) Check(sub *SpamSubmission) (SpamResult, error) {
+	count := len(commentLinkPattern.FindAllStringIndex(sub.Comment.Content, -1))
+	if count <= c.max {
+		return SpamResult{}, nil
+	}
+	return SpamResult{
+		Score:   float64(count-c.max) * spamScorePerExtraLink,
+		Reasons: []string{fmt.Sprintf("%d links (limit %d)", count, c.max)},
+	}, nil
+}
+
+type duplicateChecker struct {
+	repo   repositories.CommentRepository
+	window time.Duration
+}
+
+func (c *duplicateChecker) Name() string { return "duplicate" }
+
+func (c *duplicateChecker) Check(sub *SpamSubmission) (SpamResult, error) {
+	count, err := c.repo.CountDuplicates(sub.Comment.Content, time.Now().Add(-c.window))
+	if err != nil || count == 0 {
+		return SpamResult{}, err
+	}
+	return SpamResult{
+		Score:   spamScoreDuplicate,
+		Reasons: []string{fmt.Sprintf("same content posted %d time(s) recently", count)},
+	}, nil
+}
+
+// blocklistChecker 关键词不区分大小写；以 / 包裹的条目按正则匹配
+type blocklistChecker struct {
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+func newBlocklistChecker(entries []string) (*blocklistChecker, error) {
+	checker := &blocklistChecker{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+			pattern, err := regexp.Compile(entry[1 : len(entry)-1])
+			if err != nil {
+				return nil, err
+			}
+			checker.patterns = append(checker.patterns, pattern)
+		} else if entry != "" {
+			checker.keywords = append(checker.keywords, strings.ToLower(entry))
+		}
+	}
+	return checker, nil
+}
+
+func (c *blocklistChecker) Name() string { return "blocklist" }
+
+func (c *blocklistChecker) Check(sub *SpamSubmission) (SpamResult, error) {
+	fields := strings.Join([]string{sub.Comment.Author, sub.Comment.Email, sub.Comment.Website, sub.Comment.Content}, "\n")
+	lowered := strings.ToLower(fields)
+
+	var result SpamResult
+	for _, keyword := range c.keywords {
+		if strings.Contains(lowered, keyword) {
+			result.Score += spamScoreBlocklist
+			result.Reasons = append(result.Reasons, fmt.Sprintf("contains blocked keyword %q", keyword))
+		}
+	}
+	for _, pattern := range c.patterns {
+		if pattern.MatchString(fields) {
+			result.Score += spamScoreBlocklist
+			result.Reasons = append(result.Reasons, fmt.Sprintf("matches blocked pattern %q", pattern.String()))
+		}
+	}
+	return result, nil
+}
@@ -3,56 +3,334 @@ package services
 import (
 	"blog-system/models"
 	"blog-system/repositories"
-	"github.com/gosimple/slug"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTagSlugTaken           = errors.New("slug is already used by another tag or alias")
+	ErrTagMergeInvalid        = errors.New("merge requires source tags different from the target tag")
+	ErrTagMergeSourceNotFound = errors.New("one or more source tags do not exist")
+	ErrTagAliasNotFound       = errors.New("tag alias not found")
 )
 
+// TagCloudOptions 标签云参数
+type TagCloudOptions struct {
+	Buckets  int    // 权重分档数
+	Scale    string // linear 或 log
+	MinCount int64  // 最少文章数
+	Limit    int    // 最多返回的标签数（按文章数取前 N 个）
+}
+
+// TagCloudItem 标签云条目，Weight 取值 1..Buckets
+type TagCloudItem struct {
+	ID           uint    `json:"id"`
+	Name         string  `json:"name"`
+	Slug         string  `json:"slug"`
+	ArticleCount int64   `json:"article_count"`
+	Score        float64 `json:"score"`
+	Weight       int     `json:"weight"`
+}
+
 type TagService interface {
 	GetTags() ([]models.Tag, error)
+	GetTagsWithStats() ([]models.TagWithStats, error)
+	GetTagCloud(opts TagCloudOptions) ([]TagCloudItem, error)
+	SuggestTags(query string, limit int) ([]TagSuggestion, error)
 	GetTag(id uint) (*models.Tag, error)
 	CreateTag(input *models.Tag) (*models.Tag, error)
 	UpdateTag(id uint, input *models.Tag) (*models.Tag, error)
-	DeleteTag(id uint) error
+	RenameTag(id uint, name, customSlug string) (*models.Tag, error)
+	DeleteTag(id uint) (int64, error)
+
+	MergeTags(sourceIDs []uint, targetID uint) (*models.Tag, error)
+	GetTagAliases(id uint) ([]models.TagAlias, error)
+	AddTagAlias(id uint, name string) (*models.TagAlias, error)
+	DeleteTagAlias(aliasID uint) error
 }
 
 type tagService struct {
-	repo repositories.TagRepository
+	repo        repositories.TagRepository
+	index       *tagSuggestIndex
+	slugService SlugService
 }
 
 func NewTagService() TagService {
-	return &tagService{repo: repositories.NewTagRepository()}
+	return &tagService{
+		repo:        repositories.NewTagRepository(),
+		index:       &tagSuggestIndex{},
+		slugService: NewSlugService(),
+	}
+}
+
+// tagSlug 生成标签 slug；标签没有数字编号，策略返回空时退回拼音
+func (s *tagService) tagSlug(name string) string {
+	if value := s.slugService.Make(name); value != "" {
+		return value
+	}
+	return pinyinSlugStrategy{}.Base(name, time.Now())
+}
+
+// SuggestTags 按名称前缀、slug、别名及拼音全拼/首字母匹配标签，按使用次数排序
+func (s *tagService) SuggestTags(query string, limit int) ([]TagSuggestion, error) {
+	if s.index.stale() {
+		if err := s.rebuildIndex(); err != nil {
+			return nil, err
+		}
+	}
+	return s.index.search(query, limit), nil
+}
+
+func (s *tagService) rebuildIndex() error {
+	tags, err := s.repo.FindAllWithStats()
+	if err != nil {
+		return err
+	}
+	aliases, err := s.repo.FindAllAliases()
+	if err != nil {
+		return err
+	}
+	s.index.rebuild(tags, aliases)
+	return nil
+}
+
+// refreshIndex 标签变更后重建补全索引，失败时留待下次查询重建
+func (s *tagService) refreshIndex() {
+	if err := s.rebuildIndex(); err != nil {
+		s.index.invalidate()
+	}
 }
 
 func (s *tagService) GetTags() ([]models.Tag, error) {
 	return s.repo.FindAll()
 }
 
+func (s *tagService) GetTagsWithStats() ([]models.TagWithStats, error) {
+	return s.repo.FindAllWithStats()
+}
+
+func (s *tagService) GetTagCloud(opts TagCloudOptions) ([]TagCloudItem, error) {
+	if opts.Buckets <= 0 {
+		opts.Buckets = 5
+	}
+	if opts.MinCount <= 0 {
+		opts.MinCount = 1
+	}
+
+	tags, err := s.repo.FindAllWithStats()
+	if err != nil {
+		return nil, err
+	}
+
+	var used []models.TagWithStats
+	for _, tag := range tags {
+		if tag.ArticleCount >= opts.MinCount {
+			used = append(used, tag)
+		}
+	}
+	sort.SliceStable(used, func(i, j int) bool { return used[i].ArticleCount > used[j].ArticleCount })
+	if opts.Limit > 0 && len(used) > opts.Limit {
+		used = used[:opts.Limit]
+	}
+	if len(used) == 0 {
+		return []TagCloudItem{}, nil
+	}
+
+	scale := func(count int64) float64 { return float64(count) }
+	if opts.Scale != "linear" {
+		scale = func(count int64) float64 { return math.Log(float64(count)) }
+	}
+	maxValue := scale(used[0].ArticleCount)
+	minValue := scale(used[len(used)-1].ArticleCount)
+
+	items := make([]TagCloudItem, 0, len(used))
+	for _, tag := range used {
+		score := 0.5
+		if maxValue > minValue {
+			score = (scale(tag.ArticleCount) - minValue) / (maxValue - minValue)
+		}
+		items = append(items, TagCloudItem{
+			ID:           tag.ID,
+			Name:         tag.Name,
+			Slug:         tag.Slug,
+			ArticleCount: tag.ArticleCount,
+			Score:        math.Round(score*1000) / 1000,
+			Weight:       1 + int(math.Round(score*float64(opts.Buckets-1))),
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
 func (s *tagService) GetTag(id uint) (*models.Tag, error) {
-	return s.repo.FindByID(id)
+	tag, err := s.repo.FindByID(id)
+	if err != nil {
+		return tag, err
+	}
+	applyTagSEO(tag)
+	return tag, nil
 }
 
 func (s *tagService) CreateTag(input *models.Tag) (*models.Tag, error) {
-	input.Slug = slug.Make(input.Name)
-	err := s.repo.Create(input)
-	return input, err
+	err := s.repo.CreateWithSlug(input, s.assignSlug(input.Name, input.Slug, 0))
+	if err != nil {
+		return nil, tagSlugError(err)
+	}
+	s.refreshIndex()
+	return input, nil
 }
 
+// UpdateTag 修改名称并整体替换 SEO 信息
 func (s *tagService) UpdateTag(id uint, input *models.Tag) (*models.Tag, error) {
+	return s.renameTag(id, input.Name, "", &input.SEO)
+}
+
+// RenameTag 修改标签名称与 slug，旧 slug 保留为别名以免已有链接失效
+func (s *tagService) RenameTag(id uint, name, customSlug string) (*models.Tag, error) {
+	return s.renameTag(id, name, customSlug, nil)
+}
+
+// renameTag seo 为 nil 时保留原有 SEO 信息
+func (s *tagService) renameTag(id uint, name, customSlug string, seo *models.SEO) (*models.Tag, error) {
 	tag, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	tag.Name = input.Name
-	tag.Slug = slug.Make(input.Name)
+	// 指定了新的自定义 slug，或名称变化时重新生成 slug
+	var slugFn repositories.SlugFunc
+	if customSlug != "" && customSlug != tag.Slug {
+		slugFn = s.assignSlug("", customSlug, tag.ID)
+	} else if customSlug == "" && name != tag.Name {
+		slugFn = s.assignSlug(name, "", tag.ID)
+	}
+
+	oldName := tag.Name
+	tag.Name = name
+	if seo != nil {
+		tag.SEO = *seo
+	}
+
+	if err := s.repo.Rename(tag, oldName, slugFn); err != nil {
+		return nil, tagSlugError(err)
+	}
+	s.refreshIndex()
+	return tag, nil
+}
+
+// assignSlug 生成标签 slug：自定义 slug 被占用时报错，自动生成的 slug 冲突时追加数字后缀
+func (s *tagService) assignSlug(name, custom string, tagID uint) repositories.SlugFunc {
+	if custom != "" {
+		return s.slugService.Assign("", custom, tagID)
+	}
+	return s.slugService.Unique(s.tagSlug(name), tagID)
+}
+
+// tagSlugError 标签的 slug 冲突统一返回 ErrTagSlugTaken
+func tagSlugError(err error) error {
+	if errors.Is(err, ErrSlugTaken) {
+		return ErrTagSlugTaken
+	}
+	return err
+}
 
-	err = s.repo.Update(tag)
-	return tag, err
+// ensureSlugAvailable 检查 slug 没有被其他标签或其他标签的别名占用
+func (s *tagService) ensureSlugAvailable(tagSlug string, tagID uint) error {
+	if existing, err := s.repo.FindBySlug(tagSlug); err == nil && existing.ID != tagID {
+		return ErrTagSlugTaken
+	}
+	if alias, err := s.repo.FindAliasBySlug(tagSlug); err == nil && alias.TagID != tagID {
+		return ErrTagSlugTaken
+	}
+	return nil
 }
 
-func (s *tagService) DeleteTag(id uint) error {
+// DeleteTag 删除标签并清理文章关联，返回受影响的文章数
+func (s *tagService) DeleteTag(id uint) (int64, error) {
 	tag, err := s.repo.FindByID(id)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	affected, err := s.repo.Delete(tag)
+	if err == nil {
+		s.refreshIndex()
+	}
+	return affected, err
+}
+
+func (s *tagService) MergeTags(sourceIDs []uint, targetID uint) (*models.Tag, error) {
+	target, err := s.repo.FindByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(sourceIDs))
+	seen := make(map[uint]bool, len(sourceIDs))
+	for _, id := range sourceIDs {
+		if id != targetID && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, ErrTagMergeInvalid
+	}
+
+	if err := s.repo.Merge(ids, target.ID); err != nil {
+		if errors.Is(err, repositories.ErrTagNotFound) {
+			return nil, ErrTagMergeSourceNotFound
+		}
+		return nil, err
+	}
+	s.refreshIndex()
+	return target, nil
+}
+
+func (s *tagService) GetTagAliases(id uint) ([]models.TagAlias, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAliases(id)
+}
+
+func (s *tagService) AddTagAlias(id uint, name string) (*models.TagAlias, error) {
+	tag, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	alias := &models.TagAlias{TagID: tag.ID, Name: name, Slug: s.tagSlug(name)}
+	if alias.Slug == tag.Slug {
+		return nil, ErrTagSlugTaken
+	}
+	if err := s.ensureSlugAvailable(alias.Slug, 0); err != nil {
+		return nil, err
+	}
+
+	// 并发添加同一别名时由唯一索引兜底
+	err = s.repo.CreateAlias(alias)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrTagSlugTaken
+	}
+	if err == nil {
+		s.refreshIndex()
+	}
+	return alias, err
+}
+
+func (s *tagService) DeleteTagAlias(aliasID uint) error {
+	alias, err := s.repo.FindAliasByID(aliasID)
+	if err != nil {
+		return ErrTagAliasNotFound
+	}
+	err = s.repo.DeleteAlias(alias)
+	if err == nil {
+		s.refreshIndex()
 	}
-	return s.repo.Delete(tag)
+	return err
 }
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"blog-system/models"
+	"blog-system/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 标签建议的匹配方式
+const (
+	TagMatchName     = "name"
+	TagMatchSlug     = "slug"
+	TagMatchAlias    = "alias"
+	TagMatchPinyin   = "pinyin"
+	TagMatchInitials = "initials"
+)
+
+// tagMatchRank 使用次数相同时，数值越小的匹配方式越靠前
+var tagMatchRank = map[string]int{
+	TagMatchName:     0,
+	TagMatchSlug:     1,
+	TagMatchAlias:    2,
+	TagMatchPinyin:   3,
+	TagMatchInitials: 4,
+}
+
+// tagIndexTTL 文章增删会改变使用次数，索引超过该时间后在查询时重建
+const tagIndexTTL = 10 * time.Minute
+
+// TagSuggestion 标签自动补全结果
+type TagSuggestion struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	ArticleCount int64  `json:"article_count"`
+	Matched      string `json:"matched"`
+}
+
+type tagIndexEntry struct {
+	tag      models.TagWithStats
+	name     string
+	slug     string
+	pinyin   []string // 多音字展开后的全部全拼
+	initials []string // 多音字展开后的全部首字母
+	aliases  []string
+}
+
+// tagSuggestIndex 内存中的标签补全索引
+type tagSuggestIndex struct {
+	mu      sync.RWMutex
+	entries []tagIndexEntry
+	builtAt time.Time
+}
+
+func (idx *tagSuggestIndex) rebuild(tags []models.TagWithStats, aliases []models.TagAlias) {
+	aliasesByTag := make(map[uint][]string)
+	for _, alias := range aliases {
+		aliasesByTag[alias.TagID] = append(aliasesByTag[alias.TagID], strings.ToLower(alias.Slug), strings.ToLower(alias.Name))
+	}
+
+	entries := make([]tagIndexEntry, 0, len(tags))
+	for _, tag := range tags {
+		full, initials := utils.PinyinVariants(tag.Name)
+		entries = append(entries, tagIndexEntry{
+			tag:      tag,
+			name:     strings.ToLower(tag.Name),
+			slug:     strings.ToLower(tag.Slug),
+			pinyin:   full,
+			initials: initials,
+			aliases:  aliasesByTag[tag.ID],
+		})
+	}
+
+	idx.mu.Lock()
+	idx.entries = entries
+	idx.builtAt = time.Now()
+	idx.mu.Unlock()
+}
+
+func (idx *tagSuggestIndex) invalidate() {
+	idx.mu.Lock()
+	idx.builtAt = time.Time{}
+	idx.mu.Unlock()
+}
+
+func (idx *tagSuggestIndex) stale() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.builtAt.IsZero() || time.Since(idx.builtAt) > tagIndexTTL
+}
+
+func (idx *tagSuggestIndex) search(query string, limit int) []TagSuggestion {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return []TagSuggestion{}
+	}
+	// 拼音匹配忽略输入中的空格和分隔符
+	compact := strings.Join(utils.PinyinSegments(q), "")
+
+	idx.mu.RLock()
+	results := []TagSuggestion{}
+	for _, entry := range idx.entries {
+		matched := entry.match(q, compact)
+		if matched == "" {
+			continue
+		}
+		results = append(results, TagSuggestion{
+			ID:           entry.tag.ID,
+			Name:         entry.tag.Name,
+			Slug:         entry.tag.Slug,
+			ArticleCount: entry.tag.ArticleCount,
+			Matched:      matched,
+		})
+	}
+	idx.mu.RUnlock()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].ArticleCount != results[j].ArticleCount {
+			return results[i].ArticleCount > results[j].ArticleCount
+		}
+		if tagMatchRank[results[i].Matched] != tagMatchRank[results[j].Matched] {
+			return tagMatchRank[results[i].Matched] < tagMatchRank[results[j].Matched]
+		}
+		return results[i].Name < results[j].Name
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func (e tagIndexEntry) match(q, compact string) string {
+	switch {
+	case strings.HasPrefix(e.name, q):
+		return TagMatchName
+	case strings.HasPrefix(e.slug, q):
+		return TagMatchSlug
+	}
+	for _, alias := range e.aliases {
+		if strings.HasPrefix(alias, q) {
+			return TagMatchAlias
+		}
+	}
+	if compact == "" {
+		return ""
+	}
+	if hasAnyPrefix(e.pinyin, compact) {
+		return TagMatchPinyin
+	}
+	if hasAnyPrefix(e.initials, compact) {
+		return TagMatchInitials
+	}
+	return ""
+}
+
+func hasAnyPrefix(values []string, prefix string) bool {
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return false
+}
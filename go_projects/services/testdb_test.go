@@ -0,0 +1,31 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/database"
+	"blog-system/models"
+	"path/filepath"
+	"testing"
+)
+
+// setupTestDB 在临时目录中创建 SQLite 数据库，完成迁移和初始数据（admin/admin123、user/user123 两个账号）
+func setupTestDB(t *testing.T, cfg config.Config) {
+	t.Helper()
+	cfg.DBType = "sqlite"
+	cfg.DBName = filepath.Join(t.TempDir(), "test.db")
+	cfg.JWTSecret = "test-secret"
+	if cfg.JWTAccessTTLMinutes == 0 {
+		cfg.JWTAccessTTLMinutes = 15
+	}
+	if cfg.JWTRefreshTTLDays == 0 {
+		cfg.JWTRefreshTTLDays = 30
+	}
+	cfg.RBACDefaultRole = models.RoleReader
+	config.AppConfig = &cfg
+	database.InitDB()
+	t.Cleanup(func() {
+		if db, err := database.DB.DB(); err == nil {
+			db.Close()
+		}
+	})
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/repositories"
+	"blog-system/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOAuthProviderNotFound   = errors.New("oauth provider not found")
+	ErrInvalidOAuthState       = errors.New("invalid or expired oauth state, please start the login again")
+	ErrOAuthFailed             = errors.New("failed to sign in with the oauth provider")
+	ErrOAuthEmailRequired      = errors.New("the provider did not return a verified email address")
+	ErrOAuthSignupDisabled     = errors.New("no account is linked to this identity and sign-up through this provider is disabled")
+	ErrOAuthAccountUnverified  = errors.New("an account with this email already exists but its email is not verified; log in with your password and link the provider from your account")
+	ErrIdentityLinkedElsewhere = errors.New("this identity is already linked to another account")
+	ErrLastIdentity            = errors.New("this is your only way to sign in; set a password through password reset before unlinking it")
+)
+
+// OAuthProviderInfo 登录页展示的提供方
+type OAuthProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+}
+
+// OAuthResult 第三方登录回调的结果：登录成功返回 Tokens；启用了两步验证时返回 Challenge；关联流程只设置 Linked
+type OAuthResult struct {
+	User      *models.User
+	Tokens    *AuthTokens
+	Challenge *TwoFactorChallenge
+	Linked    bool
+	Created   bool
+}
+
+// OAuthFrontendURL 回调处理完成后跳回的前端页面
+func OAuthFrontendURL() string {
+	if config.AppConfig.OAuthRedirectURL != "" {
+		return config.AppConfig.OAuthRedirectURL
+	}
+	return siteBaseURL() + "/oauth/callback"
+}
+
+func (s *userService) OAuthProviders() []OAuthProviderInfo {
+	providers := make([]OAuthProviderInfo, 0, len(oauthProviders()))
+	for _, p := range oauthProviders() {
+		providers = append(providers, OAuthProviderInfo{Name: p.cfg.Name, DisplayName: p.cfg.DisplayName, Type: p.cfg.Type})
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers
+}
+
+func (s *userService) BeginOAuth(providerName string, linkUserID *uint) (string, string, error) {
+	provider, ok := oauthProviders()[providerName]
+	if !ok {
+		return "", "", ErrOAuthProviderNotFound
+	}
+
+	state, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthorizationURL(state, nonce, pkceChallenge(verifier))
+	if err != nil {
+		log.Printf("第三方登录 %s 初始化失败: %v", providerName, err)
+		return "", "", ErrOAuthFailed
+	}
+	record := &models.OAuthState{
+		StateHash:    hashSecret(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(time.Duration(config.AppConfig.OAuthStateTTLMinutes) * time.Minute),
+	}
+	if err := s.identityRepo.CreateState(record); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+func (s *userService) CompleteOAuth(providerName, state, boundState, code string, meta SessionMeta) (*OAuthResult, error) {
+	provider, ok := oauthProviders()[providerName]
+	if !ok {
+		return nil, ErrOAuthProviderNotFound
+	}
+	record, err := s.identityRepo.ConsumeState(hashSecret(state))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidOAuthState
+		}
+		return nil, err
+	}
+	if record.Provider != providerName {
+		return nil, ErrInvalidOAuthState
+	}
+	// state 必须与发起登录或关联的浏览器 Cookie 一致：防止攻击者把自己的授权码塞给受害者（登录 CSRF），
+	// 也防止把攻击者发起的关联链接发给受害者，让受害者的第三方身份关联到攻击者账号
+	if !hmac.Equal([]byte(state), []byte(boundState)) {
+		return nil, ErrInvalidOAuthState
+	}
+
+	identity, err := provider.Identity(code, record.CodeVerifier, record.Nonce)
+	if err != nil {
+		log.Printf("第三方登录 %s 失败: %v", providerName, err)
+		return nil, ErrOAuthFailed
+	}
+	if identity.Subject == "" {
+		log.Printf("第三方登录 %s 失败: 用户信息中没有唯一标识", providerName)
+		return nil, ErrOAuthFailed
+	}
+
+	if record.LinkUserID != nil {
+		user, err := s.linkIdentity(*record.LinkUserID, providerName, identity)
+		if err != nil {
+			return nil, err
+		}
+		return &OAuthResult{User: user, Linked: true}, nil
+	}
+
+	user, created, err := s.oauthUser(provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	result := &OAuthResult{User: user, Created: created}
+	result.Tokens, err = s.beginSession(user, meta)
+	if errors.As(err, &result.Challenge) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *userService) ListIdentities(userID uint) ([]models.UserIdentity, error) {
+	return s.identityRepo.ListByUser(userID)
+}
+
+// UnlinkIdentity 取消关联；第三方注册且从未设置密码的账号不能取消最后一个身份，否则将无法再登录
+func (s *userService) UnlinkIdentity(userID, identityID uint) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	err = s.identityRepo.Delete(userID, identityID, user.PasswordUnset)
+	if errors.Is(err, repositories.ErrLastIdentity) {
+		return ErrLastIdentity
+	}
+	return err
+}
+
+// oauthUser 查找身份对应的用户：已关联的直接登录；提供方确认过的邮箱与现有账号一致时自动关联；否则按配置注册新账号
+func (s *userService) oauthUser(provider *oauthProvider, identity *oauthIdentity) (*models.User, bool, error) {
+	providerName := provider.cfg.Name
+	existing, err := s.identityRepo.FindByProviderSubject(providerName, identity.Subject)
+	if err == nil {
+		user, err := s.repo.FindByID(existing.UserID)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing.Email != identity.Email || existing.Name != identity.Name {
+			existing.Email, existing.Name = identity.Email, identity.Name
+			if err := s.identityRepo.Update(existing); err != nil {
+				log.Printf("更新第三方身份 %d 失败: %v", existing.ID, err)
+			}
+		}
+		return user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	record := &models.UserIdentity{
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		Name:     identity.Name,
+	}
+
+	if identity.Email != "" && identity.EmailVerified {
+		user, err := s.repo.FindByEmail(identity.Email)
+		if err == nil {
+			// 本地邮箱未验证时可能是他人抢注的账号，不能凭邮箱直接关联
+			if user.EmailVerifiedAt == nil {
+				return nil, false, ErrOAuthAccountUnverified
+			}
+			record.UserID = user.ID
+			if err := s.identityRepo.Create(record); err != nil {
+				return nil, false, err
+			}
+			return user, false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+
+	if !provider.allowSignup() {
+		return nil, false, ErrOAuthSignupDisabled
+	}
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, false, ErrOAuthEmailRequired
+	}
+
+	username, err := s.availableUsername(identity)
+	if err != nil {
+		return nil, false, err
+	}
+	// 第三方注册的账号没有可用的密码，需要时可以通过找回密码设置
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, false, err
+	}
+	hashedPassword, err := utils.HashPassword(secret)
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	user := &models.User{
+		Username:        username,
+		Email:           identity.Email,
+		Password:        hashedPassword,
+		Role:            DefaultRole(),
+		EmailVerifiedAt: &now,
+		PasswordUnset:   true,
+	}
+	if err := s.identityRepo.CreateWithUser(user, record); err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+// linkIdentity 把身份关联到已登录的用户
+func (s *userService) linkIdentity(userID uint, providerName string, identity *oauthIdentity) (*models.User, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.identityRepo.FindByProviderSubject(providerName, identity.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityLinkedElsewhere
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	record := &models.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		Name:     identity.Name,
+	}
+	if err := s.identityRepo.Create(record); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// availableUsername 依次尝试提供方的用户名、昵称和邮箱前缀，重名时追加随机后缀
+func (s *userService) availableUsername(identity *oauthIdentity) (string, error) {
+	base := ""
+	for _, candidate := range []string{identity.Username, identity.Name, strings.SplitN(identity.Email, "@", 2)[0]} {
+		if base = sanitizeUsername(candidate); base != "" {
+			break
+		}
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for i := 0; i < 5; i++ {
+		_, err := s.repo.FindByUsername(username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return username, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		suffix, err := randomHex(3)
+		if err != nil {
+			return "", err
+		}
+		username = fmt.Sprintf("%s-%s", base, suffix)
+	}
+	return "", errors.New("failed to find an available username")
+}
+
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		}
+	}
+	return truncate(strings.Trim(b.String(), "_-."), 30)
+}
+
+// pkceChallenge RFC 7636 S256：BASE64URL(SHA256(code_verifier))
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"blog-system/config"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startMockOIDC 编译并启动 cmd/mockoidc，返回其 issuer
+func startMockOIDC(t *testing.T) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "mockoidc")
+	if out, err := exec.Command("go", "build", "-o", binary, "../cmd/mockoidc").CombinedOutput(); err != nil {
+		t.Fatalf("build cmd/mockoidc: %v\n%s", err, out)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	cmd := exec.Command(binary, "-addr", addr, "-client-id", "blog", "-client-secret", "secret")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	issuer := "http://" + addr
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		resp, err := http.Get(issuer + "/.well-known/openid-configuration")
+		if err == nil {
+			resp.Body.Close()
+			return issuer
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("mockoidc did not start: %v", err)
+		}
+	}
+}
+
+// authorizeWithMock 访问授权地址（可先修改其参数），返回提供方跳回时携带的 state 和授权码
+func authorizeWithMock(t *testing.T, authURL string, mutate func(url.Values)) (string, string) {
+	t.Helper()
+	target, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mutate != nil {
+		query := target.Query()
+		mutate(query)
+		target.RawQuery = query.Encode()
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(target.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("state"), location.Query().Get("code")
+}
+
+func TestOAuthRoundTripWithMockOIDC(t *testing.T) {
+	issuer := startMockOIDC(t)
+	setupTestDB(t, config.Config{
+		SiteURL:              "http://blog.test",
+		OAuthStateTTLMinutes: 10,
+		OAuthProviders: []config.OAuthProviderConfig{{
+			Name:         "mock",
+			Type:         OAuthTypeOIDC,
+			Issuer:       issuer,
+			ClientID:     "blog",
+			ClientSecret: "secret",
+		}},
+	})
+	// 提供方注册表按进程缓存，换成本测试的配置
+	oauthProviderRegistryOnce = sync.Once{}
+	t.Cleanup(func() { oauthProviderRegistryOnce = sync.Once{} })
+
+	service := NewUserService()
+	meta := SessionMeta{IP: "127.0.0.1", UserAgent: "test"}
+
+	tests := []struct {
+		name      string
+		authorize func(url.Values)                           // 在提供方授权前修改授权参数
+		submit    func(state, bound string) (string, string) // 回调时提交的 state 与浏览器 Cookie 中的 state
+		replay    bool                                       // 成功后再用同一 state 和授权码回调一次
+		wantErr   error
+	}{
+		{name: "successful login", replay: true},
+		{
+			name:    "state not bound to this browser",
+			submit:  func(state, bound string) (string, string) { return state, "another-browser" },
+			wantErr: ErrInvalidOAuthState,
+		},
+		{
+			name:    "unknown state",
+			submit:  func(state, bound string) (string, string) { return "forged", "forged" },
+			wantErr: ErrInvalidOAuthState,
+		},
+		{
+			name:      "PKCE verifier does not match the challenge",
+			authorize: func(q url.Values) { q.Set("code_challenge", pkceChallenge("attacker-verifier")) },
+			wantErr:   ErrOAuthFailed,
+		},
+		{
+			name:      "nonce does not match",
+			authorize: func(q url.Values) { q.Set("nonce", "attacker-nonce") },
+			wantErr:   ErrOAuthFailed,
+		},
+		{
+			name:      "unverified email",
+			authorize: func(q url.Values) { q.Set("login_hint", "bob@example.com"); q.Set("email_verified", "false") },
+			wantErr:   ErrOAuthEmailRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authURL, bound, err := service.BeginOAuth("mock", nil)
+			if err != nil {
+				t.Fatalf("BeginOAuth() = %v", err)
+			}
+			state, code := authorizeWithMock(t, authURL, tt.authorize)
+			if state != bound {
+				t.Fatalf("provider returned state %q, want %q", state, bound)
+			}
+			if tt.submit != nil {
+				state, bound = tt.submit(state, bound)
+			}
+
+			result, err := service.CompleteOAuth("mock", state, bound, code, meta)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompleteOAuth() = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (result.Tokens == nil || result.User.Email != "alice@example.com") {
+				t.Fatalf("CompleteOAuth() = %+v, want a session for alice@example.com", result)
+			}
+
+			if tt.replay {
+				if _, err := service.CompleteOAuth("mock", state, bound, code, meta); !errors.Is(err, ErrInvalidOAuthState) {
+					t.Errorf("replayed CompleteOAuth() = %v, want %v", err, ErrInvalidOAuthState)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrTooManyRequests   = errors.New("too many requests, please try again later")
+	ErrMailNotConfigured = errors.New("email is not configured on this server")
+)
+
+type passwordResetEmail struct {
+	emailBase
+	Username       string
+	ResetURL       string
+	ExpiresMinutes int
+}
+
+func (s *userService) ChangePassword(userID uint, oldPassword, newPassword string, meta SessionMeta) (*AuthTokens, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPasswordHash(oldPassword, user.Password) {
+		return nil, ErrWrongPassword
+	}
+
+	hashed, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	// 密码更新与吊销其他设备上的会话在同一事务中完成，当前客户端换发新令牌
+	if err := s.repo.UpdatePassword(user.ID, hashed, false); err != nil {
+		return nil, err
+	}
+	user.Password = hashed
+	user.TokenVersion++
+	return s.issueSession(user, meta)
+}
+
+func (s *userService) RequestPasswordReset(email, ip string) error {
+	if s.mail == nil {
+		return ErrMailNotConfigured
+	}
+	if !s.resetLimiter.Allow("ip:"+ip, config.AppConfig.AuthPasswordResetIPLimit) {
+		return ErrTooManyRequests
+	}
+
+	// 邮箱不存在或账号超出次数时同样返回成功，避免泄露哪些邮箱已注册
+	user, err := s.repo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !s.resetLimiter.Allow("user:"+strconv.FormatUint(uint64(user.ID), 10), config.AppConfig.AuthPasswordResetLimit) {
+		return nil
+	}
+
+	resetURL, err := s.createPasswordReset(user, ip)
+	if err != nil {
+		return err
+	}
+	s.sendPasswordResetEmail(user, resetURL)
+	return nil
+}
+
+// createPasswordReset 保存一次性重置令牌，返回重置链接
+func (s *userService) createPasswordReset(user *models.User, ip string) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.resetRepo.Create(&models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashSecret(token),
+		ExpiresAt: time.Now().Add(time.Duration(config.AppConfig.AuthPasswordResetTTLMinutes) * time.Minute),
+		IP:        ip,
+	}); err != nil {
+		return "", err
+	}
+	return siteBaseURL() + "/reset-password?token=" + url.QueryEscape(token), nil
+}
+
+func (s *userService) sendPasswordResetEmail(user *models.User, resetURL string) {
+	subject := fmt.Sprintf("[%s] 重置密码", config.AppConfig.SiteName)
+	s.sendAccountEmail(user.Email, subject, "password_reset", passwordResetEmail{
+		emailBase:      newEmailBase(subject, ""),
+		Username:       user.Username,
+		ResetURL:       resetURL,
+		ExpiresMinutes: config.AppConfig.AuthPasswordResetTTLMinutes,
+	})
+}
+
+func (s *userService) ResetPassword(token, newPassword string) error {
+	reset, err := s.resetRepo.FindByHash(hashSecret(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	hashed, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.resetRepo.Consume(reset, hashed); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	return nil
+}
+
+// sendAccountEmail 发送账号相关的事务邮件，不受通知退订影响
+func (s *userService) sendAccountEmail(to, subject, template string, data interface{}) {
+	html, text, err := renderEmail(template, data)
+	if err != nil {
+		log.Printf("渲染邮件模板 %s 失败: %v", template, err)
+		return
+	}
+	s.mail.Enqueue(&MailMessage{
+		To:      []string{to},
+		Subject: subject,
+		HTML:    html,
+		Text:    text,
+	})
+}
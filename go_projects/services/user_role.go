@@ -0,0 +1,72 @@
+package services
+
+import (
+	"blog-system/models"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	ErrLastAdmin   = errors.New("cannot remove the last admin")
+	ErrAdminOnly   = errors.New("only admins can manage admin accounts or grant the admin role")
+)
+
+func (s *userService) AssignRole(actorID, userID uint, role string) (*models.User, error) {
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	actor, err := s.authorizeUserAction(actorID, user)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.RoleAdmin && actor.Role != models.RoleAdmin {
+		return nil, ErrAdminOnly
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	if err := s.keepActiveAdmin(user); err != nil {
+		return nil, err
+	}
+
+	// 角色在每次请求时从数据库读取，修改后立即生效，无需吊销会话
+	user.Role = role
+	if err := s.repo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// authorizeUserAction 拥有 user.manage 的角色可以由配置授予非管理员，
+// 这类用户不能操作管理员账号，否则可以借禁用、重置密码等手段接管管理员；返回操作者
+func (s *userService) authorizeUserAction(actorID uint, target *models.User) (*models.User, error) {
+	actor, err := s.repo.FindByID(actorID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Role == models.RoleAdmin && actor.Role != models.RoleAdmin {
+		return nil, ErrAdminOnly
+	}
+	return actor, nil
+}
+
+// keepActiveAdmin 修改角色或禁用前检查：至少保留一个可用的管理员，否则没有人能再管理用户
+func (s *userService) keepActiveAdmin(user *models.User) error {
+	if user.Role != models.RoleAdmin || user.IsDisabled(time.Now()) {
+		return nil
+	}
+	admins, err := s.repo.CountActiveByRole(models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
@@ -5,31 +5,125 @@ import (
 	"blog-system/repositories"
 	"blog-system/utils"
 	"errors"
+	"log"
+	"time"
+)
+
+var (
+	ErrUsernameTaken   = errors.New("username already exists")
+	ErrEmailRegistered = errors.New("email already registered")
 )
 
 type UserService interface {
 	GetUser(id uint) (*models.User, error)
-	Register(username, email, password string) (*models.User, error)
-	Login(username, password string) (string, *models.User, error)
+	// Register 创建用户并发送验证邮件；邮箱已注册时返回 ErrEmailRegistered 并改为通知该邮箱，
+	// 调用方应返回与注册成功相同的响应
+	Register(username, email, password, ip string) (*models.User, error)
+	// Login 校验密码并开始新的登录会话；配置要求时未验证邮箱的用户返回 ErrEmailNotVerified，
+	// 启用了两步验证的用户返回 *TwoFactorChallenge
+	Login(username, password string, meta SessionMeta) (*AuthTokens, *models.User, error)
+	// Refresh 用刷新令牌换取新的访问令牌和刷新令牌，旧刷新令牌随即作废
+	Refresh(refreshToken string, meta SessionMeta) (*AuthTokens, *models.User, error)
+	// Logout 吊销刷新令牌所在的会话；all 为 true 时吊销该用户的全部会话
+	Logout(refreshToken string, all bool) error
+	// RevokeSessions 吊销用户全部会话，已签发的访问令牌立即失效
+	RevokeSessions(userID uint) error
+	// ChangePassword 校验旧密码后修改密码，其他会话全部退出，返回当前客户端的新令牌
+	ChangePassword(userID uint, oldPassword, newPassword string, meta SessionMeta) (*AuthTokens, error)
+	// RequestPasswordReset 向该邮箱发送一次性重置链接；邮箱未注册时也返回成功
+	RequestPasswordReset(email, ip string) error
+	// ResetPassword 使用重置令牌设置新密码，并吊销该用户全部会话
+	ResetPassword(token, newPassword string) error
+	// VerifyEmail 校验验证链接中的参数并标记邮箱已验证
+	VerifyEmail(userID, expires, signature string) (*models.User, error)
+	// ResendVerification 重新发送验证邮件；邮箱未注册或已验证时也返回成功
+	ResendVerification(email, ip string) error
+	// SetupTwoFactor 生成待确认的两步验证密钥
+	SetupTwoFactor(userID uint) (*TwoFactorSetup, error)
+	// ConfirmTwoFactor 用验证码确认并启用两步验证，返回恢复码；其他会话全部退出
+	ConfirmTwoFactor(userID uint, code string, meta SessionMeta) ([]string, *AuthTokens, error)
+	// DisableTwoFactor 校验密码和验证码（或恢复码）后关闭两步验证；其他会话全部退出，返回当前客户端的新令牌
+	DisableTwoFactor(userID uint, password, code string, meta SessionMeta) (*AuthTokens, error)
+	// RegenerateRecoveryCodes 作废旧恢复码并生成新的一组
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	// VerifyTwoFactorLogin 两步登录的第二步：校验挑战令牌和验证码（或恢复码）后开始会话
+	VerifyTwoFactorLogin(challengeToken, code string, meta SessionMeta) (*AuthTokens, *models.User, error)
+	// TwoFactorStatus 是否已启用两步验证及剩余的恢复码数量
+	TwoFactorStatus(userID uint) (bool, int64, error)
+	// OAuthProviders 已配置的第三方登录提供方
+	OAuthProviders() []OAuthProviderInfo
+	// BeginOAuth 生成 state、nonce 和 PKCE 参数，返回提供方的授权地址和 state；linkUserID 非空时为已登录用户关联身份
+	BeginOAuth(provider string, linkUserID *uint) (string, string, error)
+	// CompleteOAuth 处理提供方回调；boundState 为发起登录或关联时写入浏览器 Cookie 的 state
+	CompleteOAuth(provider, state, boundState, code string, meta SessionMeta) (*OAuthResult, error)
+	ListIdentities(userID uint) ([]models.UserIdentity, error)
+	UnlinkIdentity(userID, identityID uint) error
 	UpdateProfile(id uint, input *models.User) (*models.User, error)
+	// AssignRole 修改用户角色，不允许移除最后一个管理员；只有管理员能修改管理员的角色或授予管理员角色
+	AssignRole(actorID, userID uint, role string) (*models.User, error)
+	// ListUsers 管理后台的用户列表，filters 见 UserRepository.Search
+	ListUsers(page, pageSize int, filters map[string]interface{}) ([]repositories.UserStats, int64, error)
+	GetUserStats(id uint) (*repositories.UserStats, error)
+	// DisableUser 禁用账号并吊销全部会话；until 非空时为临时封禁，到期自动恢复。
+	// 以下几个操作的目标为管理员时，操作者也必须是管理员
+	DisableUser(actorID, userID uint, reason string, until *time.Time) (*models.User, error)
+	EnableUser(actorID, userID uint) (*models.User, error)
+	// ForcePasswordReset 作废当前密码并吊销全部会话，重置前不能登录（包括第三方登录），向用户发送重置链接；
+	// 未配置邮件时只有管理员能操作，返回重置链接由管理员转交
+	ForcePasswordReset(actorID, userID uint, ip string) (string, error)
 }
 
 type userService struct {
-	repo repositories.UserRepository
+	repo             repositories.UserRepository
+	tokenRepo        repositories.RefreshTokenRepository
+	resetRepo        repositories.PasswordResetRepository
+	recoveryRepo     repositories.RecoveryCodeRepository
+	identityRepo     repositories.UserIdentityRepository
+	mail             *MailQueue
+	resetLimiter     *rateLimiter
+	verifyLimiter    *rateLimiter
+	twoFactorLimiter *rateLimiter
 }
 
 func NewUserService() UserService {
-	return &userService{repo: repositories.NewUserRepository()}
+	return &userService{
+		repo:             repositories.NewUserRepository(),
+		tokenRepo:        repositories.NewRefreshTokenRepository(),
+		resetRepo:        repositories.NewPasswordResetRepository(),
+		recoveryRepo:     repositories.NewRecoveryCodeRepository(),
+		identityRepo:     repositories.NewUserIdentityRepository(),
+		mail:             DefaultMailQueue(),
+		resetLimiter:     newRateLimiter(time.Hour),
+		verifyLimiter:    newRateLimiter(time.Hour),
+		twoFactorLimiter: newRateLimiter(twoFactorAttemptWindow),
+	}
 }
 
 func (s *userService) GetUser(id uint) (*models.User, error) {
 	return s.repo.FindByID(id)
 }
 
-func (s *userService) Register(username, email, password string) (*models.User, error) {
+func (s *userService) Register(username, email, password, ip string) (*models.User, error) {
 	// Check if user exists
-	if _, err := s.repo.FindByUsername(username); err == nil {
-		return nil, errors.New("username already exists")
+	if existing, err := s.repo.FindByUsername(username); err == nil {
+		released, err := s.releaseStaleRegistration(existing)
+		if err != nil {
+			return nil, err
+		}
+		if !released {
+			return nil, ErrUsernameTaken
+		}
+	}
+	if existing, err := s.repo.FindByEmail(email); err == nil {
+		released, err := s.releaseStaleRegistration(existing)
+		if err != nil {
+			return nil, err
+		}
+		if !released {
+			// 不向注册请求透露邮箱已被使用，改为通知邮箱的主人
+			s.sendAccountExistsEmail(existing, ip)
+			return nil, ErrEmailRegistered
+		}
 	}
 
 	hashedPassword, err := utils.HashPassword(password)
@@ -41,29 +135,64 @@ func (s *userService) Register(username, email, password string) (*models.User,
 		Username: username,
 		Email:    email,
 		Password: hashedPassword,
-		Role:     "user",
+		Role:     DefaultRole(),
 	}
 
-	err = s.repo.Create(user)
-	return user, err
+	if err := s.repo.Create(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Printf("发送验证邮件失败（用户 %d）: %v", user.ID, err)
+	}
+	return user, nil
 }
 
-func (s *userService) Login(username, password string) (string, *models.User, error) {
+func (s *userService) Login(username, password string, meta SessionMeta) (*AuthTokens, *models.User, error) {
 	user, err := s.repo.FindByUsername(username)
 	if err != nil {
-		return "", nil, errors.New("invalid credentials")
+		return nil, nil, ErrInvalidCredentials
 	}
 
 	if !utils.CheckPasswordHash(password, user.Password) {
-		return "", nil, errors.New("invalid credentials")
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Role)
+	tokens, err := s.beginSession(user, meta)
 	if err != nil {
-		return "", nil, err
+		return nil, user, err
 	}
 
-	return token, user, nil
+	return tokens, user, nil
+}
+
+// beginSession 身份已确认（密码或第三方登录）后开始会话：检查邮箱验证要求，启用了两步验证时返回 *TwoFactorChallenge
+func (s *userService) beginSession(user *models.User, meta SessionMeta) (*AuthTokens, error) {
+	if err := sessionAllowed(user); err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		challenge, err := newTwoFactorChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		return nil, challenge
+	}
+	return s.issueSession(user, meta)
+}
+
+// sessionAllowed 检查账号当前能否登录：未被禁用、无需重置密码，且按配置已验证邮箱
+func sessionAllowed(user *models.User) error {
+	if user.IsDisabled(time.Now()) {
+		return ErrAccountDisabled
+	}
+	if user.PasswordResetRequired {
+		return ErrPasswordResetRequired
+	}
+	if user.EmailVerifiedAt == nil && EmailVerificationRequired(VerifiedEmailForLogin) {
+		return ErrEmailNotVerified
+	}
+	return nil
 }
 
 func (s *userService) UpdateProfile(id uint, input *models.User) (*models.User, error) {
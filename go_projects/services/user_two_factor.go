@@ -0,0 +1,269 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/utils"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// twoFactorMaxAttempts 每个账号在 twoFactorAttemptWindow 内最多连续输错的验证码次数
+	twoFactorMaxAttempts   = 5
+	twoFactorAttemptWindow = 15 * time.Minute
+)
+
+var (
+	ErrTwoFactorEnabled       = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled    = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupRequired = errors.New("two-factor setup has not been started")
+	ErrInvalidTwoFactorCode   = errors.New("invalid two-factor code")
+	ErrInvalidChallenge       = errors.New("invalid or expired two-factor challenge")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorChallenge 密码正确但还需要两步验证时由 Login 返回，客户端凭挑战令牌提交验证码换取会话
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int    `json:"expires_in"`
+}
+
+func (c *TwoFactorChallenge) Error() string {
+	return "two-factor authentication required"
+}
+
+// TwoFactorSetup 开始启用两步验证时返回的密钥和 otpauth 地址
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// newTwoFactorChallenge 签发挑战令牌，令牌带有 token_version，会话被吊销后同样失效
+func newTwoFactorChallenge(user *models.User) (*TwoFactorChallenge, error) {
+	ttl := time.Duration(config.AppConfig.AuthTwoFactorChallengeMinutes) * time.Minute
+	token, err := utils.GenerateChallengeToken(user.ID, user.Username, user.TokenVersion, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{ChallengeToken: token, ExpiresIn: int(ttl.Seconds())}, nil
+}
+
+func (s *userService) SetupTwoFactor(userID uint) (*TwoFactorSetup, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.repo.SetTOTPSecret(user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrTwoFactorEnabled
+	}
+	return &TwoFactorSetup{Secret: secret, URI: utils.TOTPURI(config.AppConfig.SiteName, user.Username, secret)}, nil
+}
+
+func (s *userService) ConfirmTwoFactor(userID uint, code string, meta SessionMeta) ([]string, *AuthTokens, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, nil, ErrTwoFactorSetupRequired
+	}
+	// 确认时只接受验证器生成的验证码，证明用户已经保存了密钥
+	if err := s.checkTOTP(user, code); err != nil {
+		return nil, nil, err
+	}
+
+	// 确认期间重新生成过密钥时，验证码对应的是旧密钥，不能启用
+	enabled, err := s.repo.EnableTOTP(user.ID, user.TOTPSecret, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !enabled {
+		return nil, nil, ErrTwoFactorSetupRequired
+	}
+	codes, err := s.replaceRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 只凭密码建立的其他会话全部退出，当前客户端换发新令牌
+	if err := s.tokenRepo.RevokeAllForUser(user.ID); err != nil {
+		return nil, nil, err
+	}
+	user.TokenVersion++
+	tokens, err := s.issueSession(user, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	return codes, tokens, nil
+}
+
+func (s *userService) DisableTwoFactor(userID uint, password, code string, meta SessionMeta) (*AuthTokens, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return nil, ErrWrongPassword
+	}
+	if err := s.checkSecondFactor(user, code); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DisableTOTP(user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.recoveryRepo.DeleteByUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	// 与启用时一样，其他会话全部退出，当前客户端换发新令牌
+	if err := s.tokenRepo.RevokeAllForUser(user.ID); err != nil {
+		return nil, err
+	}
+	user.TokenVersion++
+	return s.issueSession(user, meta)
+}
+
+func (s *userService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.checkSecondFactor(user, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(user.ID)
+}
+
+func (s *userService) VerifyTwoFactorLogin(challengeToken, code string, meta SessionMeta) (*AuthTokens, *models.User, error) {
+	claims, err := utils.ValidateChallengeToken(challengeToken)
+	if err != nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+	user, err := s.repo.FindByID(claims.UserID)
+	if err != nil || user.TokenVersion != claims.Version || user.TOTPEnabledAt == nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+	// 签发挑战后账号可能被禁用、被要求重置密码，需要重新检查
+	if err := sessionAllowed(user); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkSecondFactor(user, code); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.issueSession(user, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+func (s *userService) TwoFactorStatus(userID uint) (bool, int64, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return false, 0, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return false, 0, nil
+	}
+	remaining, err := s.recoveryRepo.CountUnused(user.ID)
+	return true, remaining, err
+}
+
+// checkSecondFactor 接受验证器生成的 6 位验证码或一次性恢复码
+func (s *userService) checkSecondFactor(user *models.User, code string) error {
+	normalized := strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if _, err := strconv.Atoi(normalized); err == nil && len(normalized) == 6 {
+		return s.checkTOTP(user, normalized)
+	}
+
+	key := strconv.FormatUint(uint64(user.ID), 10)
+	if !s.twoFactorLimiter.Allow(key, twoFactorMaxAttempts) {
+		return ErrTooManyRequests
+	}
+	ok, err := s.recoveryRepo.Consume(user.ID, hashRecoveryCode(normalized))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	s.twoFactorLimiter.Reset(key)
+	return nil
+}
+
+// checkTOTP 校验验证码并记录时间步，同一验证码只能使用一次
+func (s *userService) checkTOTP(user *models.User, code string) error {
+	key := strconv.FormatUint(uint64(user.ID), 10)
+	if !s.twoFactorLimiter.Allow(key, twoFactorMaxAttempts) {
+		return ErrTooManyRequests
+	}
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	advanced, err := s.repo.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidTwoFactorCode
+	}
+	user.TOTPLastStep = step
+	s.twoFactorLimiter.Reset(key)
+	return nil
+}
+
+// replaceRecoveryCodes 生成新的一组恢复码，明文只在此时返回一次
+func (s *userService) replaceRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	if err := s.recoveryRepo.Replace(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode 忽略大小写和分隔符后取哈希
+func hashRecoveryCode(code string) string {
+	return hashSecret(strings.ToLower(strings.ReplaceAll(code, "-", "")))
+}
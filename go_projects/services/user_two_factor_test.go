@@ -0,0 +1,145 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/database"
+	"blog-system/models"
+	"blog-system/utils"
+	"errors"
+	"testing"
+	"time"
+)
+
+// enableTwoFactor 为 seed 用户 user 启用两步验证，返回密钥、确认时使用的时间步和恢复码
+func enableTwoFactor(t *testing.T, service UserService, meta SessionMeta) (*models.User, string, int64, []string) {
+	t.Helper()
+	var user models.User
+	if err := database.DB.Where("username = ?", "user").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	setup, err := service.SetupTwoFactor(user.ID)
+	if err != nil {
+		t.Fatalf("SetupTwoFactor() = %v", err)
+	}
+	step := utils.TOTPStep(time.Now())
+	code, err := utils.TOTPCode(setup.Secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, _, err := service.ConfirmTwoFactor(user.ID, code, meta)
+	if err != nil {
+		t.Fatalf("ConfirmTwoFactor() = %v", err)
+	}
+	return &user, setup.Secret, step, codes
+}
+
+// loginChallenge 用密码登录，期望得到两步验证挑战
+func loginChallenge(t *testing.T, service UserService, meta SessionMeta) string {
+	t.Helper()
+	_, _, err := service.Login("user", "user123", meta)
+	var challenge *TwoFactorChallenge
+	if !errors.As(err, &challenge) {
+		t.Fatalf("Login() = %v, want a two-factor challenge", err)
+	}
+	return challenge.ChallengeToken
+}
+
+func TestTwoFactorCodeReplay(t *testing.T) {
+	setupTestDB(t, config.Config{AuthTwoFactorChallengeMinutes: 5})
+	service := NewUserService()
+	meta := SessionMeta{IP: "127.0.0.1", UserAgent: "test"}
+	user, secret, confirmedStep, recovery := enableTwoFactor(t, service, meta)
+	challenge := loginChallenge(t, service, meta)
+
+	totp := func(offset int64) string {
+		code, err := utils.TOTPCode(secret, confirmedStep+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	steps := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "code used to confirm setup", code: totp(0), wantErr: ErrInvalidTwoFactorCode},
+		{name: "code from an earlier step", code: totp(-1), wantErr: ErrInvalidTwoFactorCode},
+		{name: "code from the next step", code: totp(1)},
+		{name: "same code replayed", code: totp(1), wantErr: ErrInvalidTwoFactorCode},
+		{name: "recovery code", code: recovery[0]},
+		{name: "recovery code replayed", code: recovery[0], wantErr: ErrInvalidTwoFactorCode},
+		{name: "another recovery code", code: recovery[1]},
+	}
+
+	for _, step := range steps {
+		tokens, _, err := service.VerifyTwoFactorLogin(challenge, step.code, meta)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: VerifyTwoFactorLogin() = %v, want %v", step.name, err, step.wantErr)
+		}
+		if err == nil && tokens.AccessToken == "" {
+			t.Fatalf("%s: no session issued", step.name)
+		}
+	}
+
+	_, remaining, err := service.TwoFactorStatus(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len(recovery) - 2); remaining != want {
+		t.Errorf("remaining recovery codes = %d, want %d", remaining, want)
+	}
+}
+
+func TestVerifyTwoFactorLoginRechecksAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(user *models.User)
+		wantErr error
+	}{
+		{name: "account unchanged"},
+		{
+			name: "account disabled after the challenge",
+			prepare: func(user *models.User) {
+				now := time.Now()
+				user.DisabledAt = &now
+			},
+			wantErr: ErrAccountDisabled,
+		},
+		{
+			name:    "password reset required after the challenge",
+			prepare: func(user *models.User) { user.PasswordResetRequired = true },
+			wantErr: ErrPasswordResetRequired,
+		},
+		{
+			name:    "sessions revoked after the challenge",
+			prepare: func(user *models.User) { user.TokenVersion++ },
+			wantErr: ErrInvalidChallenge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t, config.Config{AuthTwoFactorChallengeMinutes: 5})
+			service := NewUserService()
+			meta := SessionMeta{IP: "127.0.0.1", UserAgent: "test"}
+			user, _, _, recovery := enableTwoFactor(t, service, meta)
+			challenge := loginChallenge(t, service, meta)
+
+			if tt.prepare != nil {
+				if err := database.DB.First(user, user.ID).Error; err != nil {
+					t.Fatal(err)
+				}
+				tt.prepare(user)
+				if err := database.DB.Save(user).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, _, err := service.VerifyTwoFactorLogin(challenge, recovery[0], meta); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyTwoFactorLogin() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
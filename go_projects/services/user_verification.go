@@ -0,0 +1,183 @@
+package services
+
+import (
+	"blog-system/config"
+	"blog-system/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 可以要求先验证邮箱的操作，对应 auth.require_verified_email
+const (
+	VerifiedEmailForLogin   = "login"
+	VerifiedEmailForComment = "comment"
+	VerifiedEmailForAuthor  = "author"
+)
+
+var (
+	ErrEmailNotVerified  = errors.New("email address has not been verified")
+	ErrInvalidVerifyLink = errors.New("invalid verification link")
+	ErrVerifyLinkExpired = errors.New("verification link has expired, please request a new one")
+)
+
+type accountExistsEmail struct {
+	emailBase
+	Username       string
+	ResetURL       string
+	ExpiresMinutes int
+}
+
+type emailVerificationEmail struct {
+	emailBase
+	Username    string
+	VerifyURL   string
+	ExpiresHour int
+}
+
+// EmailVerificationRequired 配置是否要求在执行该操作前验证邮箱
+func EmailVerificationRequired(action string) bool {
+	for _, required := range config.AppConfig.AuthRequireVerifiedEmail {
+		if strings.EqualFold(strings.TrimSpace(required), action) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendVerificationEmail 发送验证邮件；未启用邮件时返回 ErrMailNotConfigured
+func (s *userService) sendVerificationEmail(user *models.User) error {
+	if s.mail == nil {
+		return ErrMailNotConfigured
+	}
+
+	ttl := config.AppConfig.AuthEmailVerificationTTLHours
+	expires := time.Now().Add(time.Duration(ttl) * time.Hour).Unix()
+	query := url.Values{}
+	query.Set("uid", strconv.FormatUint(uint64(user.ID), 10))
+	query.Set("exp", strconv.FormatInt(expires, 10))
+	query.Set("sig", verificationSignature(user, expires))
+
+	subject := fmt.Sprintf("[%s] 验证你的邮箱", config.AppConfig.SiteName)
+	s.sendAccountEmail(user.Email, subject, "email_verification", emailVerificationEmail{
+		emailBase:   newEmailBase(subject, ""),
+		Username:    user.Username,
+		VerifyURL:   siteBaseURL() + "/api/auth/verify-email?" + query.Encode(),
+		ExpiresHour: ttl,
+	})
+	return nil
+}
+
+func (s *userService) VerifyEmail(userID, expires, signature string) (*models.User, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidVerifyLink
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidVerifyLink
+	}
+
+	user, err := s.repo.FindByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidVerifyLink
+		}
+		return nil, err
+	}
+	// 签名包含邮箱，修改邮箱后旧链接自动失效
+	if !hmac.Equal([]byte(signature), []byte(verificationSignature(user, exp))) {
+		return nil, ErrInvalidVerifyLink
+	}
+	if user.EmailVerifiedAt != nil {
+		return user, nil
+	}
+	if time.Now().Unix() > exp {
+		return nil, ErrVerifyLinkExpired
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := s.repo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userService) ResendVerification(email, ip string) error {
+	if s.mail == nil {
+		return ErrMailNotConfigured
+	}
+	if !s.verifyLimiter.Allow("ip:"+ip, config.AppConfig.AuthVerificationResendIPLimit) {
+		return ErrTooManyRequests
+	}
+
+	// 邮箱未注册、已验证或超出次数时同样返回成功，避免泄露账号状态
+	user, err := s.repo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	if !s.verifyLimiter.Allow("user:"+strconv.FormatUint(uint64(user.ID), 10), config.AppConfig.AuthVerificationResendLimit) {
+		return nil
+	}
+	return s.sendVerificationEmail(user)
+}
+
+// releaseStaleRegistration 登录要求验证邮箱时，超过验证有效期仍未验证的注册从未能登录，
+// 删除后释放其用户名和邮箱；返回是否已删除
+func (s *userService) releaseStaleRegistration(user *models.User) (bool, error) {
+	if user.EmailVerifiedAt != nil || !EmailVerificationRequired(VerifiedEmailForLogin) {
+		return false, nil
+	}
+	ttl := time.Duration(config.AppConfig.AuthEmailVerificationTTLHours) * time.Hour
+	if time.Since(user.CreatedAt) <= ttl {
+		return false, nil
+	}
+	return s.repo.DeleteUnverified(user.ID)
+}
+
+// sendAccountExistsEmail 有人用已注册的邮箱注册时通知邮箱的主人，并附上重置密码链接；
+// 账号未验证时，重置密码同时完成邮箱验证，邮箱的主人可以借此收回被抢注的账号
+func (s *userService) sendAccountExistsEmail(user *models.User, ip string) {
+	if s.mail == nil {
+		return
+	}
+	if !s.resetLimiter.Allow("user:"+strconv.FormatUint(uint64(user.ID), 10), config.AppConfig.AuthPasswordResetLimit) {
+		return
+	}
+	resetURL, err := s.createPasswordReset(user, ip)
+	if err != nil {
+		log.Printf("创建重置链接失败（用户 %d）: %v", user.ID, err)
+		return
+	}
+
+	subject := fmt.Sprintf("[%s] 该邮箱已注册", config.AppConfig.SiteName)
+	s.sendAccountEmail(user.Email, subject, "account_exists", accountExistsEmail{
+		emailBase:      newEmailBase(subject, ""),
+		Username:       user.Username,
+		ResetURL:       resetURL,
+		ExpiresMinutes: config.AppConfig.AuthPasswordResetTTLMinutes,
+	})
+}
+
+// verificationSignature 用 JWT 密钥对用户 ID、邮箱和过期时间签名，验证链接无需落库
+func verificationSignature(user *models.User, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWTSecret))
+	fmt.Fprintf(mac, "verify-email:%d:%s:%d", user.ID, strings.ToLower(user.Email), expires)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
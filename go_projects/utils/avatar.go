@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"blog-system/config"
+	"crypto/md5"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// EmailHash Gravatar 规范的邮箱哈希：去空格、转小写后取 MD5
+func EmailHash(email string) string {
+	sum := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// AvatarURL 根据邮箱生成 Gravatar/Cravatar 兼容的头像地址，镜像在配置中指定
+func AvatarURL(email string) string {
+	mirror := config.AppConfig.AvatarMirror
+	if !strings.HasSuffix(mirror, "/") {
+		mirror += "/"
+	}
+	query := url.Values{}
+	query.Set("d", config.AppConfig.AvatarDefault)
+	query.Set("s", strconv.Itoa(config.AppConfig.AvatarSize))
+	return mirror + EmailHash(email) + "?" + query.Encode()
+}
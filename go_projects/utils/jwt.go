@@ -2,6 +2,7 @@ package utils
 
 import (
 	"blog-system/config"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,16 +12,47 @@ type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Version  int    `json:"ver"` // 对应 User.TokenVersion，版本不一致的令牌视为已吊销
+	// Purpose 非空表示专用令牌（如两步验证的挑战令牌），不能当作访问令牌使用
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, username, role string) (string, error) {
+// TwoFactorPurpose 两步验证挑战令牌的用途
+const TwoFactorPurpose = "2fa"
+
+var ErrTokenPurpose = errors.New("token cannot be used for this purpose")
+
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL() time.Duration {
+	return time.Duration(config.AppConfig.JWTAccessTTLMinutes) * time.Minute
+}
+
+func GenerateToken(userID uint, username, role string, version int) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
+		Version:  version,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.AppConfig.JWTSecret))
+}
+
+// GenerateChallengeToken 密码校验通过、等待两步验证时签发的短期令牌
+func GenerateChallengeToken(userID uint, username string, version int, ttl time.Duration) (string, error) {
+	claims := Claims{
+		UserID:   userID,
+		Username: username,
+		Version:  version,
+		Purpose:  TwoFactorPurpose,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -29,7 +61,31 @@ func GenerateToken(userID uint, username, role string) (string, error) {
 	return token.SignedString([]byte(config.AppConfig.JWTSecret))
 }
 
+// ValidateChallengeToken 校验两步验证挑战令牌
+func ValidateChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != TwoFactorPurpose {
+		return nil, ErrTokenPurpose
+	}
+	return claims, nil
+}
+
+// ValidateToken 校验访问令牌，拒绝挑战令牌等专用令牌
 func ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrTokenPurpose
+	}
+	return claims, nil
+}
+
+func parseToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(config.AppConfig.JWTSecret), nil
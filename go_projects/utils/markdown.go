@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// commentLinkRel 评论中的链接一律标记为用户生成内容，不传递权重
+const commentLinkRel = "nofollow ugc"
+
+var commentLinkCountKey = parser.NewContextKey()
+
+// commentMarkdown 评论使用的受限 Markdown：只保留段落、强调、链接、行内/围栏代码和引用，
+// 标题、列表、图片和原始 HTML 都不解析
+var commentMarkdown = goldmark.New(
+	goldmark.WithParser(parser.NewParser(
+		parser.WithBlockParsers(
+			util.Prioritized(parser.NewCodeBlockParser(), 500),
+			util.Prioritized(parser.NewFencedCodeBlockParser(), 700),
+			util.Prioritized(parser.NewBlockquoteParser(), 800),
+			util.Prioritized(parser.NewParagraphParser(), 1000),
+		),
+		parser.WithInlineParsers(
+			util.Prioritized(parser.NewCodeSpanParser(), 100),
+			util.Prioritized(parser.NewLinkParser(), 200),
+			util.Prioritized(parser.NewAutoLinkParser(), 300),
+			util.Prioritized(parser.NewEmphasisParser(), 500),
+			util.Prioritized(extension.NewLinkifyParser(), 999),
+		),
+		parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
+		parser.WithASTTransformers(util.Prioritized(commentLinkTransformer{}, 100)),
+	)),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// commentPolicy 渲染结果的白名单，防止解析器遗漏的内容造成 XSS
+var commentPolicy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "em", "strong", "code", "pre", "blockquote")
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("rel").Matching(regexp.MustCompile(`^` + commentLinkRel + `$`)).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	return p
+}()
+
+// commentLinkTransformer 给链接加上 rel，把图片降级为普通链接，并统计链接数
+type commentLinkTransformer struct{}
+
+func (commentLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var images []*ast.Image
+	count := 0
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Image:
+			images = append(images, n)
+		case *ast.Link:
+			// javascript: 等危险地址不会输出 href，净化后只剩文字，不计入链接数
+			if !html.IsDangerousURL(n.Destination) {
+				n.SetAttributeString("rel", []byte(commentLinkRel))
+				count++
+			}
+		case *ast.AutoLink:
+			n.SetAttributeString("rel", []byte(commentLinkRel))
+			count++
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, image := range images {
+		link := ast.NewLink()
+		link.Destination = image.Destination
+		link.Title = image.Title
+		if !html.IsDangerousURL(image.Destination) {
+			link.SetAttributeString("rel", []byte(commentLinkRel))
+			count++
+		}
+		for child := image.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			link.AppendChild(link, child)
+			child = next
+		}
+		image.Parent().ReplaceChild(image.Parent(), image, link)
+	}
+	pc.Set(commentLinkCountKey, count)
+}
+
+// RenderCommentMarkdown 将评论的 Markdown 渲染为净化后的 HTML，同时返回其中的链接数
+func RenderCommentMarkdown(source string) (string, int, error) {
+	var buf bytes.Buffer
+	ctx := parser.NewContext()
+	if err := commentMarkdown.Convert([]byte(source), &buf, parser.WithContext(ctx)); err != nil {
+		return "", 0, err
+	}
+	links, _ := ctx.Get(commentLinkCountKey).(int)
+	return commentPolicy.Sanitize(buf.String()), links, nil
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+var pinyinArgs = pinyin.NewArgs()
+
+// heteronymArgs 返回多音字的全部读音
+var heteronymArgs = func() pinyin.Args {
+	args := pinyin.NewArgs()
+	args.Heteronym = true
+	return args
+}()
+
+// maxPinyinVariants 多音字读音组合数上限，超出后其余多音字只取最常用的读音
+const maxPinyinVariants = 64
+
+// pinyinSegmentReadings 将文本拆分为小写的拼音音节，每个音节给出全部候选读音（第一个最常用）；
+// 连续的字母数字保留为一个单词
+func pinyinSegmentReadings(text string, args pinyin.Args) [][]string {
+	var segments [][]string
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() > 0 {
+			segments = append(segments, []string{word.String()})
+			word.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			if py := pinyin.SinglePinyin(r, args); len(py) > 0 {
+				segments = append(segments, py)
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return segments
+}
+
+// PinyinSegments 将文本拆分为小写的拼音音节，汉字转为不带声调的拼音，连续的字母数字保留为一个单词；
+// 多音字只取最常用的读音，如 "调" 为 diao
+func PinyinSegments(text string) []string {
+	readings := pinyinSegmentReadings(text, pinyinArgs)
+	segments := make([]string, 0, len(readings))
+	for _, r := range readings {
+		segments = append(segments, r[0])
+	}
+	return segments
+}
+
+// PinyinVariants 展开多音字的全部读音组合，返回去重后的全拼和首字母；
+// 同时包括从每个音节开始的后缀，以便匹配名称中间的词。
+// 如 "数据库调优" 的全拼包括 shujukudiaoyou、shujukutiaoyou、tiaoyou，首字母包括 sjkdy、sjkty、ty
+func PinyinVariants(text string) ([]string, []string) {
+	segments := pinyinSegmentReadings(text, heteronymArgs)
+	var full, initials []string
+	for start := range segments {
+		f, i := expandPinyin(segments[start:])
+		full = append(full, f...)
+		initials = append(initials, i...)
+	}
+	return uniqueStrings(full), uniqueStrings(initials)
+}
+
+// expandPinyin 展开各音节候选读音的组合
+func expandPinyin(segments [][]string) ([]string, []string) {
+	full := []string{""}
+	initials := []string{""}
+	for _, readings := range segments {
+		if len(full)*len(readings) > maxPinyinVariants {
+			readings = readings[:1]
+		}
+		nextFull := make([]string, 0, len(full)*len(readings))
+		nextInitials := make([]string, 0, len(full)*len(readings))
+		for i := range full {
+			for _, reading := range readings {
+				nextFull = append(nextFull, full[i]+reading)
+				nextInitials = append(nextInitials, initials[i]+string([]rune(reading)[0]))
+			}
+		}
+		full, initials = nextFull, nextInitials
+	}
+	return full, initials
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := values[:0]
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}